@@ -33,11 +33,27 @@ type Measurer[Value, Measure any] interface {
 }
 
 func wrapTree[MS Measurer[V, M], V, M any](tree fingerTree) FingerTree[MS, V, M] {
+	if tree == zeroTree {
+		return FingerTree[MS, V, M]{}
+	}
 	return FingerTree[MS, V, M]{tree}
 }
 
+// A zero FingerTree acts like an empty tree whose measurer panics with ErrZeroTree,
+// so everything that does not need to measure works on it.
+var zeroTree fingerTree = &emptyTree{measurement{zeroMeasurer{}, nil}}
+
+func (t FingerTree[MS, V, M]) tree() fingerTree {
+	if t.f == nil {
+		return zeroTree
+	}
+	return t.f
+}
+
 var ErrBadValue = fmt.Errorf("%w, bad value", ErrFingerTree)
 
+var ErrOutOfRange = fmt.Errorf("%w, index out of range", ErrFingerTree)
+
 func wrapPredicate[M any](pred Predicate[M]) func(any) bool {
 	return func(m any) bool {
 		if wm, ok := m.(M); !ok {
@@ -64,31 +80,39 @@ func null[T any]() T {
 
 // Add a value to the start of the tree.
 func (t FingerTree[MS, V, M]) AddFirst(value V) FingerTree[MS, V, M] {
-	return wrapTree[MS, V, M](t.f.AddFirst(value))
+	return wrapTree[MS, V, M](t.tree().AddFirst(value))
 }
 
 // Add a value to the and of the tree.
 func (t FingerTree[MS, V, M]) AddLast(value V) FingerTree[MS, V, M] {
-	return wrapTree[MS, V, M](t.f.AddLast(value))
+	return wrapTree[MS, V, M](t.tree().AddLast(value))
+}
+
+// Add a value to the end of the tree and return whether this made the spine deeper,
+// which indicates a rebalancing cost.
+func (t FingerTree[MS, V, M]) AddLastDepth(value V) (FingerTree[MS, V, M], bool) {
+	before := spineDepth(t.tree())
+	result := t.tree().AddLast(value)
+	return wrapTree[MS, V, M](result), spineDepth(result) > before
 }
 
 // Remove the first value in the tree. Make sure to test whether the tree is empty
 // because this will panic if it is.
 func (t FingerTree[MS, V, M]) RemoveFirst() FingerTree[MS, V, M] {
-	return wrapTree[MS, V, M](t.f.RemoveFirst())
+	return wrapTree[MS, V, M](t.tree().RemoveFirst())
 }
 
 // Remove the last value in the tree. Make sure to test whether the tree is empty
 // because this will panic if it is.
 func (t FingerTree[MS, V, M]) RemoveLast() FingerTree[MS, V, M] {
-	return wrapTree[MS, V, M](t.f.RemoveLast())
+	return wrapTree[MS, V, M](t.tree().RemoveLast())
 }
 
 // Return the first value in the tree. Make sure to test whether the tree is empty
 // because this will panic if it is.
 func (t FingerTree[MS, V, M]) PeekFirst() V {
-	if cv, ok := t.f.PeekFirst().(V); !ok {
-		panic(fmt.Errorf("%w, first value in tree: %v", ErrBadValue, t.f.PeekFirst()))
+	if cv, ok := t.tree().PeekFirst().(V); !ok {
+		panic(fmt.Errorf("%w, first value in tree: %v", ErrBadValue, t.tree().PeekFirst()))
 	} else {
 		return cv
 	}
@@ -97,28 +121,108 @@ func (t FingerTree[MS, V, M]) PeekFirst() V {
 // Return the last value in the tree. Make sure to test whether the tree is empty
 // because this will panic if it is.
 func (t FingerTree[MS, V, M]) PeekLast() V {
-	if cv, ok := t.f.PeekLast().(V); !ok {
-		panic(fmt.Errorf("%w, last value in tree: %v", ErrBadValue, t.f.PeekLast()))
+	if cv, ok := t.tree().PeekLast().(V); !ok {
+		panic(fmt.Errorf("%w, last value in tree: %v", ErrBadValue, t.tree().PeekLast()))
 	} else {
 		return cv
 	}
 }
 
+// Return the value at index in O(log n). This panics if index is out of range.
+func (t FingerTree[MS, V, M]) Get(index int) V {
+	if index < 0 || index >= t.tree().size() {
+		panic(fmt.Errorf("%w: %d in tree of length %d", ErrOutOfRange, index, t.tree().size()))
+	}
+	return valueAt(t.tree(), index).(V)
+}
+
+// Return the value at index and its neighbors, if present, using one split.
+// This panics if index is out of range.
+func (t FingerTree[MS, V, M]) Neighborhood(index int) (prev, cur, next V, hasPrev, hasNext bool) {
+	if index < 0 || index >= t.tree().size() {
+		panic(fmt.Errorf("%w: %d in tree of length %d", ErrOutOfRange, index, t.tree().size()))
+	}
+	left, v, right := t.tree().splitIndex(index)
+	cur = v.(V)
+	if hasPrev = !isEmpty(left); hasPrev {
+		prev = left.PeekLast().(V)
+	}
+	if hasNext = !isEmpty(right); hasNext {
+		next = right.PeekFirst().(V)
+	}
+	return
+}
+
 // Join two finger trees together
+// If t is strict and other is not, this uses a strict copy of other.
 func (t FingerTree[MS, V, M]) Concat(other FingerTree[MS, V, M]) FingerTree[MS, V, M] {
-	return wrapTree[MS, V, M](t.f.Concat(other.f))
+	if t.IsStrict() && !other.IsStrict() {
+		other = other.Strict()
+	}
+	return wrapTree[MS, V, M](t.tree().Concat(other.tree()))
 }
 
 // Split the tree. The first tree is all the starting values that do not satisfy the predicate.
 // The second tree is the first value that satisfies the predicate, followed by the rest of the values.
+// If the predicate is true for the identity measure, the first tree is empty and the second
+// is the whole tree. If it is never true, the first tree is the whole tree.
+// When CheckSplits is true, this panics if SplitStrict would return an error.
 func (t FingerTree[MS, V, M]) Split(predicate Predicate[M]) (FingerTree[MS, V, M], FingerTree[MS, V, M]) {
-	left, right := t.f.Split(wrapPredicate(predicate))
+	if CheckSplits {
+		left, right, err := t.SplitStrict(predicate)
+		if err != nil {
+			panic(err)
+		}
+		return left, right
+	}
+	left, right := t.tree().Split(wrapPredicate(predicate))
+	return wrapTree[MS, V, M](left), wrapTree[MS, V, M](right)
+}
+
+// Set CheckSplits to true to make Split check its predicate the way SplitStrict does.
+// This costs a few extra measurements per split so it is meant for debugging.
+var CheckSplits = false
+
+var ErrBadPredicate = fmt.Errorf("%w, predicate is not monotonic", ErrFingerTree)
+
+// Split the tree like Split but return an ErrBadPredicate error if the predicate
+// is obviously not monotonic: it must be false for the measure of the left tree and
+// true once the first value of the right tree is added to it. Split returns garbage
+// for predicates like that. A predicate that is true for the identity measure is fine
+// and splits off an empty first tree, like Split.
+func (t FingerTree[MS, V, M]) SplitStrict(predicate Predicate[M]) (FingerTree[MS, V, M], FingerTree[MS, V, M], error) {
+	if isEmpty(t.tree()) {
+		return t, t, nil
+	}
+	meas := measurerFor(t.tree())
+	pred := wrapPredicate(predicate)
+	if pred(meas.Identity()) {
+		return wrapTree[MS, V, M](empty(t.tree())), t, nil
+	}
+	left, right := t.tree().Split(pred)
+	if !isEmpty(right) {
+		leftMeasure := left.measurement().value
+		if pred(leftMeasure) {
+			return FingerTree[MS, V, M]{}, FingerTree[MS, V, M]{}, fmt.Errorf("%w: true for the measure of the left tree, %v", ErrBadPredicate, leftMeasure)
+		}
+		boundary := meas.Sum(leftMeasure, meas.Measure(right.PeekFirst()))
+		if !pred(boundary) {
+			return FingerTree[MS, V, M]{}, FingerTree[MS, V, M]{}, fmt.Errorf("%w: false at the split point, %v", ErrBadPredicate, boundary)
+		}
+	}
+	return wrapTree[MS, V, M](left), wrapTree[MS, V, M](right), nil
+}
+
+// Split the tree into two halves by count, using the cached counts rather than a
+// predicate. When the length is odd, the left half gets the extra value.
+func (t FingerTree[MS, V, M]) SplitHalf() (FingerTree[MS, V, M], FingerTree[MS, V, M]) {
+	left, right := splitAt(t.tree(), (t.tree().size()+1)/2)
 	return wrapTree[MS, V, M](left), wrapTree[MS, V, M](right)
 }
 
 // Return a slice containing all of the values in the tree
 func (t FingerTree[MS, V, M]) ToSlice() []V {
-	s := t.f.ToSlice()
+	s := t.tree().ToSlice()
 	result := make([]V, len(s))
 	for i := 0; i < len(s); i++ {
 		result[i] = s[i].(V)
@@ -126,50 +230,152 @@ func (t FingerTree[MS, V, M]) ToSlice() []V {
 	return result
 }
 
+// Return a slice containing all of the values in the tree in reverse order
+func (t FingerTree[MS, V, M]) ToSliceReverse() []V {
+	result := make([]V, 0, t.tree().size())
+	t.tree().EachReverse(func(v any) bool {
+		result = append(result, v.(V))
+		return true
+	})
+	return result
+}
+
+// Return whether this is a zero FingerTree rather than one made by a constructor.
+// A zero tree acts like an empty tree for operations that do not measure anything,
+// like Each, ToSlice, Len, and Split, and the others panic with ErrZeroTree.
 func (t FingerTree[MS, V, M]) IsZero() bool {
 	return t.f == nil
 }
 
 func (t FingerTree[MS, V, M]) String() string {
+	if t.f == nil {
+		return "zeroTree{}"
+	}
 	return t.f.String()
 }
 
 func (t FingerTree[MS, V, M]) Dump(w io.Writer, level int) {
-	t.f.Dump(w, level)
+	t.tree().Dump(w, level)
 }
 
 // Return whether the tree is empty
 func (t FingerTree[MS, V, M]) IsEmpty() bool {
-	return isEmpty(t.f)
+	return isEmpty(t.tree())
+}
+
+// Return the number of values in the tree. Counts are cached along with
+// measures so this does not iterate.
+func (t FingerTree[MS, V, M]) Len() int {
+	return t.tree().size()
 }
 
 // Return the measure of all the tree's values
 func (t FingerTree[MS, V, M]) Measure() M {
-	if cm, ok := t.f.measurement().value.(M); !ok {
-		panic(fmt.Errorf("%w, measurement in tree: %v", ErrBadValue, t.f.measurement().value))
+	if t.f == nil {
+		panic(fmt.Errorf("%w: cannot call Measure", ErrZeroTree))
+	}
+	if cm, ok := t.tree().measurement().value.(M); !ok {
+		panic(fmt.Errorf("%w, measurement in tree: %v", ErrBadValue, t.tree().measurement().value))
 	} else {
 		return cm
 	}
 }
 
-// Return all the initial values in the tree that do not satisfy the predicate
+// Return all the initial values in the tree that do not satisfy the predicate, the first
+// tree Split returns. This is empty if the predicate is true for the identity measure.
 func (t FingerTree[MS, V, M]) TakeUntil(pred Predicate[M]) FingerTree[MS, V, M] {
-	return wrapTree[MS, V, M](takeUntil(t.f, wrapPredicate(pred)))
+	return wrapTree[MS, V, M](takeUntil(t.tree(), wrapPredicate(pred)))
 }
 
-// Discard all the initial values in the tree that do not satisfy the predicate
+// Discard all the initial values in the tree that do not satisfy the predicate, returning
+// the second tree Split returns. This is the whole tree if the predicate is true for the
+// identity measure.
 func (t FingerTree[MS, V, M]) DropUntil(pred Predicate[M]) FingerTree[MS, V, M] {
-	return wrapTree[MS, V, M](dropUntil(t.f, wrapPredicate(pred)))
+	return wrapTree[MS, V, M](dropUntil(t.tree(), wrapPredicate(pred)))
+}
+
+// Remove the initial values that do not satisfy the predicate, returning them in a new
+// slice along with the rest of the tree, like ToSlice of TakeUntil with DropUntil. This
+// splits the tree once and copies the values out of the left half. If the predicate is
+// never true, this returns all the values and an empty tree.
+func (t FingerTree[MS, V, M]) PopUntil(pred Predicate[M]) ([]V, FingerTree[MS, V, M]) {
+	first, rest := t.Split(pred)
+	return first.ToSlice(), rest
+}
+
+// Split off the longest prefix whose total weight does not exceed budget, returning
+// it, the rest of the tree, and the unused budget. Weights should not be negative.
+func (t FingerTree[MS, V, M]) TakeWeight(budget float64, weight func(V) float64) (FingerTree[MS, V, M], FingerTree[MS, V, M], float64) {
+	count := 0
+	t.Each(func(v V) bool {
+		w := weight(v)
+		if w > budget {
+			return false
+		}
+		budget -= w
+		count++
+		return true
+	})
+	taken, rest := splitAt(t.tree(), count)
+	return wrapTree[MS, V, M](taken), wrapTree[MS, V, M](rest), budget
+}
+
+// Split the tree into the longest prefix of values that satisfy pred and the rest,
+// which starts with the first value that does not. Values after that one are not
+// visited, and if it is in the left digit the rest of the tree stays lazy.
+func (t FingerTree[MS, V, M]) Span(pred func(V) bool) (FingerTree[MS, V, M], FingerTree[MS, V, M]) {
+	count := 0
+	t.Each(func(v V) bool {
+		if pred(v) {
+			count++
+			return true
+		}
+		return false
+	})
+	prefix, rest := splitAt(t.tree(), count)
+	return wrapTree[MS, V, M](prefix), wrapTree[MS, V, M](rest)
+}
+
+// Discard the initial values until pred holds, keeping the first value where it
+// does and everything after it. Unlike DropUntil this tests values, not measures.
+func (t FingerTree[MS, V, M]) DropUntilValue(pred func(V) bool) FingerTree[MS, V, M] {
+	_, rest := t.Span(func(v V) bool { return !pred(v) })
+	return rest
+}
+
+// Discard the initial values that satisfy drop
+func (t FingerTree[MS, V, M]) DropWhile(drop func(V) bool) FingerTree[MS, V, M] {
+	_, rest := t.Span(drop)
+	return rest
+}
+
+// Discard the final values that satisfy drop
+func (t FingerTree[MS, V, M]) DropLastWhile(drop func(V) bool) FingerTree[MS, V, M] {
+	count := 0
+	t.EachReverse(func(v V) bool {
+		if drop(v) {
+			count++
+			return true
+		}
+		return false
+	})
+	rest, _ := splitAt(t.tree(), t.tree().size()-count)
+	return wrapTree[MS, V, M](rest)
+}
+
+// Discard the initial and final values that satisfy drop, like strings.TrimFunc
+func (t FingerTree[MS, V, M]) Trim(drop func(V) bool) FingerTree[MS, V, M] {
+	return t.DropWhile(drop).DropLastWhile(drop)
 }
 
 // Iterate through the tree starting at the beginning
 func (t FingerTree[MS, V, M]) Each(iter IterFunc[V]) {
-	t.f.Each(wrapIter(iter))
+	t.tree().Each(wrapIter(iter))
 }
 
 // Iterate through the tree starting at the end
 func (t FingerTree[MS, V, M]) EachReverse(iter IterFunc[V]) {
-	t.f.EachReverse(wrapIter(iter))
+	t.tree().EachReverse(wrapIter(iter))
 }
 
 // The measurer interface
@@ -185,6 +391,14 @@ type adaptedMeasurer[MS Measurer[V, M], V, M any] struct {
 	am MS
 }
 
+// Return the internal measurer for a user measurer.
+func adapt[MS Measurer[V, M], V, M any](measurer MS) measurer {
+	if _, ok := any(measurer).(NoMeasurer[V]); ok {
+		return unmeasured{measurer}
+	}
+	return adaptedMeasurer[MS, V, M]{measurer}
+}
+
 func (m adaptedMeasurer[MS, V, M]) Identity() any {
 	return m.am.Identity()
 }
@@ -212,18 +426,227 @@ func (m adaptedMeasurer[MS, V, M]) Sum(a any, b any) any {
 // So you should just be able to say,
 //
 //	t := FromArray(myMeasurer, []Plant{plant1, plant2})
-func FromArray[MS Measurer[V, M], V, M any](measurer MS, values []V) FingerTree[MS, V, M] {
+//
+// Options like [WithInterning] affect how values are stored.
+func FromArray[MS Measurer[V, M], V, M any](measurer MS, values []V, opts ...BuildOption[V]) FingerTree[MS, V, M] {
+	intern := buildOptions(opts).intern
 	cvt := make([]any, len(values))
 	for i := 0; i < len(values); i++ {
-		cvt[i] = values[i]
+		cvt[i] = intern(values[i])
+	}
+	return wrapTree[MS, V, M](fromArray(adapt[MS, V, M](measurer), cvt))
+}
+
+// Return a tree of values and a tree of values in reverse, converting values in one
+// pass. The reverse tree's measures are computed separately because Sum may not be
+// commutative.
+func FromArrayBoth[MS Measurer[V, M], V, M any](measurer MS, values []V) (forward, reverse FingerTree[MS, V, M]) {
+	fwd := make([]any, len(values))
+	rev := make([]any, len(values))
+	for i, v := range values {
+		fwd[i] = v
+		rev[len(values)-1-i] = v
 	}
-	return wrapTree[MS, V, M](fromArray(adaptedMeasurer[MS, V, M]{measurer}, cvt))
+	meas := adapt[MS, V, M](measurer)
+	return wrapTree[MS, V, M](fromArray(meas, fwd)), wrapTree[MS, V, M](fromArray(meas, rev))
 }
 
+// Join any number of trees in order. Empty and zero trees are skipped and the rest
+// are joined in a balanced way, pairing neighbors, so joining many trees keeps the
+// intermediate trees shallow and each join only costs the log of the smaller tree.
+// Joining nothing returns a zero tree and joining only empty trees returns the first
+// of them. Strictness follows the first non-empty tree, like [FingerTree.Concat].
 func Concat[MS Measurer[V, M], V, M any](trees ...FingerTree[MS, V, M]) FingerTree[MS, V, M] {
-	result := newEmptyTree(trees[0].f.measurement().measurer)
+	var first FingerTree[MS, V, M]
+	parts := make([]FingerTree[MS, V, M], 0, len(trees))
 	for _, t := range trees {
-		result = result.Concat(t.f)
+		if t.f == nil {
+			continue
+		} else if first.f == nil {
+			first = t
+		}
+		if !isEmpty(t.tree()) {
+			parts = append(parts, t)
+		}
+	}
+	if len(parts) == 0 {
+		return first
+	}
+	for len(parts) > 1 {
+		joined := parts[:0]
+		for i := 0; i < len(parts); i += 2 {
+			if i+1 < len(parts) {
+				joined = append(joined, parts[i].Concat(parts[i+1]))
+			} else {
+				joined = append(joined, parts[i])
+			}
+		}
+		parts = joined
+	}
+	return parts[0]
+}
+
+// Create a strict finger tree. Strict trees never defer work, so Concat, Split, and
+// removals evaluate eagerly. This trades a slightly higher average cost for the
+// absence of occasional long pauses when a chain of deferred work gets forced.
+// Trees derived from a strict tree are also strict and concatenating a
+// lazy tree onto a strict one makes a strict copy of the lazy one.
+func FromArrayStrict[MS Measurer[V, M], V, M any](measurer MS, values []V) FingerTree[MS, V, M] {
+	b := newBuilder[MS, V, M](strictMeasurer{adapt[MS, V, M](measurer)})
+	b.AddSlice(values)
+	return b.Tree()
+}
+
+// Return a strict copy of the tree, see [FromArrayStrict]. This rebuilds the tree
+// unless it is already strict.
+func (t FingerTree[MS, V, M]) Strict() FingerTree[MS, V, M] {
+	meas := measurerFor(t.tree())
+	if isStrict(meas) {
+		return t
+	}
+	b := newBuilder[MS, V, M](strictMeasurer{meas})
+	t.tree().Each(func(v any) bool {
+		b.pending = append(b.pending, v)
+		return true
+	})
+	return b.Tree()
+}
+
+// Return whether the tree is strict, see [FromArrayStrict].
+func (t FingerTree[MS, V, M]) IsStrict() bool {
+	return isStrict(measurerFor(t.tree()))
+}
+
+// Force up to budget suspensions, outermost first, to pay down lazy work a little at
+// a time, like during idle frames. This returns the tree, which is usable at every
+// step, and whether unforced suspensions remain. Forced suspensions keep their
+// results so calling this repeatedly always makes progress.
+func (t FingerTree[MS, V, M]) ForceN(budget int) (FingerTree[MS, V, M], bool) {
+	return t, forceN(t.tree(), budget)
+}
+
+// Describe each level of the tree's spine and its suspensions without forcing
+// anything, to see where pending work lives. Only mid trees are lazy and an unforced
+// suspension hides the levels below it, so a pending level is always the last one.
+func (t FingerTree[MS, V, M]) LazyProfile() []LevelInfo {
+	return lazyProfile(t.tree())
+}
+
+// Return the number of unforced suspensions that can be seen without forcing any.
+// A suspension hides the rest of the spine below it, but a Concat suspension knows
+// how many earlier joins forcing it runs, so those are counted too.
+func (t FingerTree[MS, V, M]) SuspensionCount() int {
+	count := 0
+	for _, level := range t.LazyProfile() {
+		count += level.Debt
+	}
+	return count
+}
+
+// Write the tree's spine to w, one level per line, marking pending and forced
+// suspensions without forcing anything, see [FingerTree.LazyProfile].
+func (t FingerTree[MS, V, M]) DumpLazy(w io.Writer) {
+	for _, level := range t.LazyProfile() {
+		fmt.Fprintf(w, "%*s%d: %s", level.Depth*2, "", level.Depth, level.Kind)
+		if level.Kind == "deep" {
+			fmt.Fprintf(w, " left %d right %d", level.Left, level.Right)
+			if !level.Measured {
+				fmt.Fprint(w, " unmeasured")
+			}
+		}
+		if level.Kind == "pending" {
+			fmt.Fprintf(w, " suspension from %s", level.Op)
+			if level.Debt > 1 {
+				fmt.Fprintf(w, " with %d unforced suspensions", level.Debt)
+			}
+		} else if level.Forced == 1 {
+			fmt.Fprintf(w, " forced suspension from %s", level.Op)
+		} else if level.Forced > 1 {
+			fmt.Fprintf(w, " %d forced suspensions, the last from %s", level.Forced, level.Op)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Return the cached measures of the tree's parts level nodes below its top-level
+// items, in order. The top-level items are the values and nodes the digits along the
+// tree's spine hold, so level 0 is the root's immediate children and each level
+// replaces nodes with their children. Values above the level count as themselves, so
+// every level's measures add up to the tree's measure. This forces the spine and
+// panics if level is negative.
+func (t FingerTree[MS, V, M]) LevelMeasures(level int) []M {
+	if level < 0 {
+		panic(fmt.Errorf("%w: LevelMeasures level %d", ErrOutOfRange, level))
+	}
+	meas := measurerFor(t.tree())
+	var result []M
+	var add func(item any, depth int)
+	add = func(item any, depth int) {
+		if n, ok := item.(*node); !ok {
+			result = append(result, meas.Measure(item).(M))
+		} else if depth == level {
+			result = append(result, n._measurement.value.(M))
+		} else {
+			for _, child := range n.children {
+				add(child, depth+1)
+			}
+		}
+	}
+	var rights []*digit
+	for tree := t.tree(); ; {
+		switch tr := force(tree).(type) {
+		case *singleTree:
+			add(tr.value, 0)
+		case *deepTree:
+			for _, item := range tr.left.items {
+				add(item, 0)
+			}
+			rights = append(rights, tr.right)
+			tree = tr.mid
+			continue
+		}
+		break
+	}
+	for i := len(rights) - 1; i >= 0; i-- {
+		for _, item := range rights[i].items {
+			add(item, 0)
+		}
+	}
+	return result
+}
+
+func (m adaptedMeasurer[MS, V, M]) userMeasurer() any {
+	return m.am
+}
+
+// Return the measurer a tree was created with.
+func userMeasurer(m measurer) any {
+	for {
+		switch mm := m.(type) {
+		case strictMeasurer:
+			m = mm.measurer
+		case interface{ userMeasurer() any }:
+			return mm.userMeasurer()
+		default:
+			return m
+		}
+	}
+}
+
+// Convert a tree to a different measurer type without rebuilding it. This is unsafe:
+// the tree keeps using its original measurer, so only use this when the two measurer
+// types behave identically. [RewrapChecked] at least verifies that the original
+// measurer is an MS2.
+func Rewrap[MS2 Measurer[V, M], MS Measurer[V, M], V, M any](t FingerTree[MS, V, M]) FingerTree[MS2, V, M] {
+	return wrapTree[MS2, V, M](t.tree())
+}
+
+// Convert a tree to a different measurer type without rebuilding it, returning an
+// ErrBadMeasurer error if the tree's measurer is not an MS2.
+func RewrapChecked[MS2 Measurer[V, M], MS Measurer[V, M], V, M any](t FingerTree[MS, V, M]) (FingerTree[MS2, V, M], error) {
+	um := userMeasurer(measurerFor(t.tree()))
+	if _, ok := um.(MS2); !ok {
+		return FingerTree[MS2, V, M]{}, fmt.Errorf("%w: %T is not a %T", ErrBadMeasurer, um, null[MS2]())
 	}
-	return wrapTree[MS, V, M](result)
+	return Rewrap[MS2](t), nil
 }
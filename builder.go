@@ -0,0 +1,171 @@
+package lazyfingertree
+
+import (
+	"fmt"
+	"iter"
+)
+
+var ErrBadRecord = fmt.Errorf("%w, bad record", ErrFingerTree)
+
+// A Builder accumulates values and builds a tree from them in bulk.
+// This is cheaper than adding the values to a tree one at a time.
+type Builder[MS Measurer[V, M], V, M any] struct {
+	measurer measurer
+	tree     fingerTree
+	pending  []any
+	intern   func(V) V
+}
+
+// A BuildOption changes how FromArray or a Builder stores values.
+type BuildOption[V any] func(*buildConfig[V])
+
+type buildConfig[V any] struct {
+	intern func(V) V
+}
+
+func buildOptions[V any](opts []BuildOption[V]) *buildConfig[V] {
+	config := &buildConfig[V]{intern: func(v V) V { return v }}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// Store one canonical value for each key so repeated values share memory, which
+// helps when V is a string, pointer, or slice with many repeats. The interning
+// table holds at most capacity values, after which new keys are stored as they
+// are; capacity <= 0 means no limit. The table belongs to FromArray or the
+// Builder, the trees they build do not keep it.
+func WithInterning[V any, K comparable](key func(V) K, capacity int) BuildOption[V] {
+	return func(config *buildConfig[V]) {
+		table := make(map[K]V, max(capacity, 0))
+		config.intern = func(v V) V {
+			k := key(v)
+			if canonical, ok := table[k]; ok {
+				return canonical
+			} else if capacity <= 0 || len(table) < capacity {
+				table[k] = v
+			}
+			return v
+		}
+	}
+}
+
+// Create a builder for trees that use measurer.
+func NewBuilder[MS Measurer[V, M], V, M any](measurer MS, opts ...BuildOption[V]) *Builder[MS, V, M] {
+	b := newBuilder[MS, V, M](adapt[MS, V, M](measurer))
+	b.intern = buildOptions(opts).intern
+	return b
+}
+
+func newBuilder[MS Measurer[V, M], V, M any](measurer measurer) *Builder[MS, V, M] {
+	return &Builder[MS, V, M]{
+		measurer: measurer,
+		tree:     newEmptyTree(measurer),
+		intern:   func(v V) V { return v },
+	}
+}
+
+// Add a value to the end of the tree being built.
+func (b *Builder[MS, V, M]) Add(value V) {
+	b.pending = append(b.pending, b.intern(value))
+}
+
+// Add values to the end of the tree being built.
+func (b *Builder[MS, V, M]) AddSlice(values []V) {
+	for _, v := range values {
+		b.pending = append(b.pending, b.intern(v))
+	}
+}
+
+// Add all of a tree's values to the end of the tree being built. This splices the
+// tree in with a concatenation instead of adding its values one by one, so it does
+// not intern them. A lazy tree added to a strict builder is copied to keep the
+// result strict, like FingerTree.Concat.
+func (b *Builder[MS, V, M]) AddTree(t FingerTree[MS, V, M]) {
+	if isEmpty(t.tree()) {
+		return
+	}
+	if isStrict(b.measurer) && !t.IsStrict() {
+		t = t.Strict()
+	}
+	b.flush()
+	b.tree = b.tree.Concat(t.f)
+}
+
+// Build a tree from records, such as the rows of a CSV file, using parse to turn each
+// record into a value. This stops at the first record that fails to parse, returning an
+// ErrBadRecord error that names its index and wraps the parse error.
+func FromRecords[MS Measurer[V, M], V, M any](measurer MS, records [][]string, parse func([]string) (V, error)) (FingerTree[MS, V, M], error) {
+	b := NewBuilder(measurer)
+	for i, record := range records {
+		v, err := parse(record)
+		if err != nil {
+			return FingerTree[MS, V, M]{}, fmt.Errorf("%w %d: %w", ErrBadRecord, i, err)
+		}
+		b.Add(v)
+	}
+	return b.Tree(), nil
+}
+
+// Build a tree from keys and vals consumed in lockstep, using combine to turn each
+// pair into a value. This stops at the end of the shorter sequence.
+func FromKVSeq[MS Measurer[V, M], K, W, V, M any](measurer MS, keys iter.Seq[K], vals iter.Seq[W], combine func(K, W) V) FingerTree[MS, V, M] {
+	b := NewBuilder(measurer)
+	next, stop := iter.Pull(vals)
+	defer stop()
+	for k := range keys {
+		v, ok := next()
+		if !ok {
+			break
+		}
+		b.Add(combine(k, v))
+	}
+	return b.Tree()
+}
+
+// Return the number of values added so far.
+func (b *Builder[MS, V, M]) Len() int {
+	return b.tree.size() + len(b.pending)
+}
+
+// Return a tree of all the values added so far. The builder can continue to be
+// used afterwards, the returned tree is not affected by later additions.
+func (b *Builder[MS, V, M]) Tree() FingerTree[MS, V, M] {
+	b.flush()
+	return wrapTree[MS, V, M](b.tree)
+}
+
+func (b *Builder[MS, V, M]) flush() {
+	if len(b.pending) > 0 {
+		b.tree = b.tree.Concat(buildTree(b.measurer, b.pending))
+		b.pending = nil
+	}
+}
+
+// Build a tree directly from items in linear time.
+// The middle items are grouped into nodes and built into the mid tree.
+func buildTree(meas measurer, items []any) fingerTree {
+	switch {
+	case len(items) == 0:
+		return newEmptyTree(meas)
+	case len(items) == 1:
+		return newSingleTree(meas, items[0])
+	case len(items) <= 8:
+		half := len(items) / 2
+		return newDeepTree(meas,
+			newDigit(meas, items[:half]),
+			makeEmptyMid(meas),
+			newDigit(meas, items[half:]))
+	}
+	last := len(items) - 3
+	nodes := nodes(meas, items[3:last])
+	midItems := make([]any, len(nodes))
+	for i, n := range nodes {
+		midItems[i] = n
+	}
+	return newDeepTree(meas,
+		newDigit(meas, items[:3]),
+		buildTree(newNodeMeasurer(meas), midItems),
+		newDigit(meas, items[last:]))
+}
@@ -0,0 +1,163 @@
+package lazyfingertree
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrBadEncoding = fmt.Errorf("%w, bad encoding", ErrFingerTree)
+
+// The version of the CBOR snapshot format that WriteCBOR produces.
+// A snapshot is a CBOR array holding the format version and an array with a byte
+// string for each value, so the element count comes before the values.
+const CBORVersion = 1
+
+const (
+	cborUint  = 0
+	cborBytes = 2
+	cborArray = 4
+)
+
+// Encode the tree's values in order as a CBOR snapshot, see [WriteCBOR].
+func (t FingerTree[MS, V, M]) MarshalCBOR(encodeValue func(v V) ([]byte, error)) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.WriteCBOR(&buf, encodeValue); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write the tree's values in order to w as a CBOR snapshot, using encodeValue for
+// each value. This streams the values so it does not hold the whole encoding in
+// memory. If a value fails to encode, the returned ErrBadEncoding error names its index.
+func (t FingerTree[MS, V, M]) WriteCBOR(w io.Writer, encodeValue func(v V) ([]byte, error)) error {
+	bw := bufio.NewWriter(w)
+	writeCBORHead(bw, cborArray, 2)
+	writeCBORHead(bw, cborUint, CBORVersion)
+	writeCBORHead(bw, cborArray, uint64(t.Len()))
+	index := 0
+	var err error
+	t.Each(func(v V) bool {
+		var data []byte
+		if data, err = encodeValue(v); err != nil {
+			err = fmt.Errorf("%w: value %d: %w", ErrBadEncoding, index, err)
+			return false
+		}
+		writeCBORHead(bw, cborBytes, uint64(len(data)))
+		bw.Write(data)
+		index++
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// Build a tree from a CBOR snapshot made by [MarshalCBOR], see [ReadCBOR].
+func UnmarshalCBOR[MS Measurer[V, M], V, M any](measurer MS, data []byte, decodeValue func([]byte) (V, error)) (FingerTree[MS, V, M], error) {
+	return ReadCBOR(measurer, bytes.NewReader(data), decodeValue)
+}
+
+// Read a CBOR snapshot made by [WriteCBOR] from r and build a tree of its values in
+// bulk, using decodeValue for each value. This returns an ErrBadEncoding error for an
+// unsupported version, malformed data, or a value that fails to decode, naming its index.
+func ReadCBOR[MS Measurer[V, M], V, M any](measurer MS, r io.Reader, decodeValue func([]byte) (V, error)) (FingerTree[MS, V, M], error) {
+	var none FingerTree[MS, V, M]
+	br, ok := r.(cborReader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	if _, err := readCBORHead(br, cborArray, "snapshot"); err != nil {
+		return none, err
+	}
+	version, err := readCBORHead(br, cborUint, "version")
+	if err != nil {
+		return none, err
+	} else if version != CBORVersion {
+		return none, fmt.Errorf("%w: unsupported version %d", ErrBadEncoding, version)
+	}
+	count, err := readCBORHead(br, cborArray, "values")
+	if err != nil {
+		return none, err
+	}
+	b := NewBuilder(measurer)
+	var buf bytes.Buffer
+	for i := uint64(0); i < count; i++ {
+		size, err := readCBORHead(br, cborBytes, "value")
+		if err != nil {
+			return none, fmt.Errorf("%w: value %d", err, i)
+		}
+		buf.Reset()
+		// copy rather than allocating size bytes up front, in case size is garbage
+		if _, err := io.CopyN(&buf, br, int64(size)); err != nil {
+			return none, fmt.Errorf("%w: value %d: %w", ErrBadEncoding, i, err)
+		}
+		v, err := decodeValue(buf.Bytes())
+		if err != nil {
+			return none, fmt.Errorf("%w: value %d: %w", ErrBadEncoding, i, err)
+		}
+		b.Add(v)
+	}
+	return b.Tree(), nil
+}
+
+type cborReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func writeCBORHead(w *bufio.Writer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		w.WriteByte(major | byte(n))
+	case n <= 0xff:
+		w.Write([]byte{major | 24, byte(n)})
+	case n <= 0xffff:
+		w.Write([]byte{major | 25, byte(n >> 8), byte(n)})
+	case n <= 0xffffffff:
+		w.Write([]byte{major | 26, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+	default:
+		w.WriteByte(major | 27)
+		for shift := 56; shift >= 0; shift -= 8 {
+			w.WriteByte(byte(n >> shift))
+		}
+	}
+}
+
+// Read a CBOR item head of the given major type and return its argument.
+func readCBORHead(r cborReader, major byte, what string) (uint64, error) {
+	initial, err := r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("%w: reading %s: %w", ErrBadEncoding, what, noEOF(err))
+	} else if initial>>5 != major {
+		return 0, fmt.Errorf("%w: expected %s with major type %d but got %d", ErrBadEncoding, what, major, initial>>5)
+	}
+	info := initial & 0x1f
+	if info < 24 {
+		return uint64(info), nil
+	} else if info > 27 {
+		return 0, fmt.Errorf("%w: unsupported %s length encoding %d", ErrBadEncoding, what, info)
+	}
+	n := uint64(0)
+	for i := 0; i < 1<<(info-24); i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("%w: reading %s: %w", ErrBadEncoding, what, noEOF(err))
+		}
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+// Inside a snapshot, running out of data is an error.
+func noEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package lazyfingertree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func encodeInt(v int) ([]byte, error) {
+	return binary.AppendVarint(nil, int64(v)), nil
+}
+
+func decodeInt(data []byte) (int, error) {
+	v, n := binary.Varint(data)
+	if n != len(data) {
+		return 0, fmt.Errorf("bad varint %x", data)
+	}
+	return int(v), nil
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 23, 24, 300, 70000} {
+		nums := make([]int, size)
+		for i := range nums {
+			nums[i] = i*7 - 1000
+		}
+		data, err := newTree(nums...).MarshalCBOR(encodeInt)
+		failIfNot(t, err == nil)
+		tree, err := UnmarshalCBOR(newWidth[int](), data, decodeInt)
+		failIfNot(t, err == nil && same(tree.ToSlice(), nums) && tree.Measure() == size)
+	}
+	words := []string{"", "a", strings.Repeat("long", 100)}
+	var buf bytes.Buffer
+	err := FromArray(newWidth[string](), words).WriteCBOR(&buf, func(s string) ([]byte, error) {
+		return []byte(s), nil
+	})
+	failIfNot(t, err == nil)
+	tree, err := ReadCBOR(newWidth[string](), io.MultiReader(&buf), func(b []byte) (string, error) {
+		return string(b), nil
+	})
+	failIfNot(t, err == nil && same(tree.ToSlice(), words))
+}
+
+// Version 1 snapshots must keep decoding as the format evolves.
+func TestCBORVersion1(t *testing.T) {
+	v1 := []byte{0x82, 0x01, 0x83, 0x41, 0x02, 0x41, 0x03, 0x42, 0xc8, 0x01}
+	tree, err := UnmarshalCBOR(newWidth[int](), v1, decodeInt)
+	failIfNot(t, err == nil && same(tree.ToSlice(), []int{1, -2, 100}))
+	data, err := newTree(1, -2, 100).MarshalCBOR(encodeInt)
+	failIfNot(t, err == nil && bytes.Equal(data, v1))
+	v2 := []byte{0x82, 0x02, 0x80}
+	_, err = UnmarshalCBOR(newWidth[int](), v2, decodeInt)
+	failIfNot(t, errors.Is(err, ErrBadEncoding) && strings.Contains(err.Error(), "unsupported version 2"))
+}
+
+func TestCBORErrors(t *testing.T) {
+	_, err := newTree(1, 2, 3, 4).MarshalCBOR(func(v int) ([]byte, error) {
+		if v == 3 {
+			return nil, strconv.ErrRange
+		}
+		return encodeInt(v)
+	})
+	failIfNot(t, errors.Is(err, ErrBadEncoding) && errors.Is(err, strconv.ErrRange))
+	failIfNot(t, err != nil && strings.Contains(err.Error(), "value 2"))
+	data, _ := newTree(1, 2, 3, 4).MarshalCBOR(encodeInt)
+	_, err = UnmarshalCBOR(newWidth[int](), data, func(b []byte) (int, error) {
+		v, _ := decodeInt(b)
+		if v == 4 {
+			return 0, strconv.ErrSyntax
+		}
+		return v, nil
+	})
+	failIfNot(t, errors.Is(err, ErrBadEncoding) && errors.Is(err, strconv.ErrSyntax))
+	failIfNot(t, err != nil && strings.Contains(err.Error(), "value 3"))
+	for cut := 0; cut < len(data); cut++ {
+		_, err = UnmarshalCBOR(newWidth[int](), data[:cut], decodeInt)
+		failIfNot(t, errors.Is(err, ErrBadEncoding))
+	}
+	_, err = UnmarshalCBOR(newWidth[int](), []byte{0x82, 0x01, 0x81, 0x01}, decodeInt)
+	failIfNot(t, errors.Is(err, ErrBadEncoding) && strings.Contains(err.Error(), "value 0"))
+	_, err = UnmarshalCBOR(newWidth[int](), []byte{0x82, 0x01, 0x9f}, decodeInt)
+	failIfNot(t, errors.Is(err, ErrBadEncoding))
+	huge := []byte{0x82, 0x01, 0x81, 0x5b, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00}
+	_, err = UnmarshalCBOR(newWidth[int](), huge, decodeInt)
+	failIfNot(t, errors.Is(err, ErrBadEncoding) && errors.Is(err, io.EOF))
+}
@@ -0,0 +1,237 @@
+package lazyfingertree
+
+import "fmt"
+
+// DefaultChunkSize is the leaf size Chunked trees use when none is given.
+const DefaultChunkSize = 16
+
+// A Chunked tree holds the same sequences as a FingerTree but stores its values in
+// leaf arrays of up to chunkSize values instead of one tree element per value.
+// For small values like ints this uses several times less memory and makes
+// iteration much faster, at the cost of copying a leaf on each edit.
+// The measure of a Chunked tree is the same as a FingerTree of its values.
+type Chunked[MS Measurer[V, M], V, M any] struct {
+	tree      FingerTree[chunkMeasurer[MS, V, M], leaf[V, M], chunkMeasure[M]]
+	chunkSize int
+}
+
+// A leaf is an immutable array of values with their cached measure.
+type leaf[V, M any] struct {
+	values  []V
+	measure M
+}
+
+type chunkMeasure[M any] struct {
+	count   int
+	measure M
+}
+
+type chunkMeasurer[MS Measurer[V, M], V, M any] struct {
+	measurer MS
+}
+
+func (m chunkMeasurer[MS, V, M]) Identity() chunkMeasure[M] {
+	return chunkMeasure[M]{0, m.measurer.Identity()}
+}
+
+func (m chunkMeasurer[MS, V, M]) Measure(l leaf[V, M]) chunkMeasure[M] {
+	return chunkMeasure[M]{len(l.values), l.measure}
+}
+
+func (m chunkMeasurer[MS, V, M]) Sum(a chunkMeasure[M], b chunkMeasure[M]) chunkMeasure[M] {
+	return chunkMeasure[M]{a.count + b.count, m.measurer.Sum(a.measure, b.measure)}
+}
+
+func (m chunkMeasurer[MS, V, M]) leaf(values []V) leaf[V, M] {
+	measure := m.measurer.Identity()
+	for _, v := range values {
+		measure = m.measurer.Sum(measure, m.measurer.Measure(v))
+	}
+	return leaf[V, M]{values, measure}
+}
+
+// Create a chunked tree of values with leaves of up to chunkSize values.
+// If chunkSize < 1, this uses DefaultChunkSize.
+func FromArrayChunked[MS Measurer[V, M], V, M any](measurer MS, values []V, chunkSize int) Chunked[MS, V, M] {
+	if chunkSize < 1 {
+		chunkSize = DefaultChunkSize
+	}
+	meas := chunkMeasurer[MS, V, M]{measurer}
+	b := NewBuilder(meas)
+	for start := 0; start < len(values); start += chunkSize {
+		end := min(start+chunkSize, len(values))
+		b.Add(meas.leaf(append([]V(nil), values[start:end]...)))
+	}
+	return Chunked[MS, V, M]{b.Tree(), chunkSize}
+}
+
+func (c Chunked[MS, V, M]) meas() chunkMeasurer[MS, V, M] {
+	return c.tree.measurer()
+}
+
+func (c Chunked[MS, V, M]) with(tree FingerTree[chunkMeasurer[MS, V, M], leaf[V, M], chunkMeasure[M]]) Chunked[MS, V, M] {
+	return Chunked[MS, V, M]{tree, c.chunkSize}
+}
+
+// Return the number of values in the tree.
+func (c Chunked[MS, V, M]) Len() int {
+	return c.tree.Measure().count
+}
+
+// Return whether the tree is empty.
+func (c Chunked[MS, V, M]) IsEmpty() bool {
+	return c.tree.IsEmpty()
+}
+
+// Return the measure of all the values in the tree.
+func (c Chunked[MS, V, M]) Measure() M {
+	return c.tree.Measure().measure
+}
+
+// Iterate through the values in order, stopping when iter returns false.
+func (c Chunked[MS, V, M]) Each(iter IterFunc[V]) {
+	c.tree.Each(func(l leaf[V, M]) bool {
+		for _, v := range l.values {
+			if !iter(v) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Return the values in a slice.
+func (c Chunked[MS, V, M]) ToSlice() []V {
+	result := make([]V, 0, c.Len())
+	c.tree.Each(func(l leaf[V, M]) bool {
+		result = append(result, l.values...)
+		return true
+	})
+	return result
+}
+
+// Return the value at index. This panics if index is out of range.
+func (c Chunked[MS, V, M]) Get(index int) V {
+	if index < 0 || index >= c.Len() {
+		panic(fmt.Errorf("%w: %d in tree of length %d", ErrOutOfRange, index, c.Len()))
+	}
+	loc := locate(c.tree.f, wrapPredicate(func(m chunkMeasure[M]) bool {
+		return m.count > index
+	}), c.meas().Identity())
+	return loc.value.(leaf[V, M]).values[index-loc.before.(chunkMeasure[M]).count]
+}
+
+// Return a tree with value added to the end, filling the last leaf if it has room.
+func (c Chunked[MS, V, M]) AddLast(value V) Chunked[MS, V, M] {
+	meas := c.meas()
+	if !c.tree.IsEmpty() {
+		if last := c.tree.PeekLast(); len(last.values) < c.chunkSize {
+			values := append(append(make([]V, 0, len(last.values)+1), last.values...), value)
+			return c.with(c.tree.RemoveLast().AddLast(meas.leaf(values)))
+		}
+	}
+	return c.with(c.tree.AddLast(meas.leaf([]V{value})))
+}
+
+// Return a tree with value added to the front, filling the first leaf if it has room.
+func (c Chunked[MS, V, M]) AddFirst(value V) Chunked[MS, V, M] {
+	meas := c.meas()
+	if !c.tree.IsEmpty() {
+		if first := c.tree.PeekFirst(); len(first.values) < c.chunkSize {
+			values := append(append(make([]V, 0, len(first.values)+1), value), first.values...)
+			return c.with(c.tree.RemoveFirst().AddFirst(meas.leaf(values)))
+		}
+	}
+	return c.with(c.tree.AddFirst(meas.leaf([]V{value})))
+}
+
+// Return the first value. Make sure the tree is not empty.
+func (c Chunked[MS, V, M]) PeekFirst() V {
+	return c.tree.PeekFirst().values[0]
+}
+
+// Return the last value. Make sure the tree is not empty.
+func (c Chunked[MS, V, M]) PeekLast() V {
+	values := c.tree.PeekLast().values
+	return values[len(values)-1]
+}
+
+// Return a tree without its first value. Like FingerTree.RemoveFirst, this panics
+// if the tree is empty.
+func (c Chunked[MS, V, M]) RemoveFirst() Chunked[MS, V, M] {
+	rest := c.tree.RemoveFirst()
+	first := c.tree.PeekFirst()
+	if len(first.values) > 1 {
+		rest = rest.AddFirst(c.meas().leaf(first.values[1:]))
+	}
+	return c.with(rest)
+}
+
+// Return a tree without its last value. Like FingerTree.RemoveLast, this panics
+// if the tree is empty.
+func (c Chunked[MS, V, M]) RemoveLast() Chunked[MS, V, M] {
+	rest := c.tree.RemoveLast()
+	last := c.tree.PeekLast()
+	if len(last.values) > 1 {
+		rest = rest.AddLast(c.meas().leaf(last.values[:len(last.values)-1]))
+	}
+	return c.with(rest)
+}
+
+// Join two trees. The leaves where they meet are merged if they fit in one leaf.
+func (c Chunked[MS, V, M]) Concat(other Chunked[MS, V, M]) Chunked[MS, V, M] {
+	if c.tree.IsEmpty() {
+		return other
+	} else if other.tree.IsEmpty() {
+		return c
+	}
+	left, right := c.tree, other.tree
+	last, first := left.PeekLast(), right.PeekFirst()
+	if len(last.values)+len(first.values) <= c.chunkSize {
+		values := append(append(make([]V, 0, len(last.values)+len(first.values)), last.values...), first.values...)
+		left = left.RemoveLast().AddLast(c.meas().leaf(values))
+		right = right.RemoveFirst()
+	}
+	return c.with(left.Concat(right))
+}
+
+// Split the tree where pred becomes true for the measure of the values up to and
+// including a value, like FingerTree.Split. That value starts the second tree.
+// If pred is true for the identity measure, the first tree is empty.
+func (c Chunked[MS, V, M]) Split(pred Predicate[M]) (Chunked[MS, V, M], Chunked[MS, V, M]) {
+	left, right := c.tree.Split(func(m chunkMeasure[M]) bool {
+		return pred(m.measure)
+	})
+	if right.IsEmpty() {
+		return c.with(left), c.with(right)
+	}
+	meas := c.meas()
+	l := right.PeekFirst()
+	acc := left.Measure().measure
+	i := 0
+	for ; i < len(l.values)-1; i++ {
+		acc = meas.measurer.Sum(acc, meas.measurer.Measure(l.values[i]))
+		if pred(acc) {
+			break
+		}
+	}
+	if i > 0 {
+		left = left.AddLast(meas.leaf(l.values[:i:i]))
+		right = right.RemoveFirst().AddFirst(meas.leaf(l.values[i:]))
+	}
+	return c.with(left), c.with(right)
+}
+
+// Split the tree into the first index values and the rest.
+func (c Chunked[MS, V, M]) SplitAt(index int) (Chunked[MS, V, M], Chunked[MS, V, M]) {
+	left, right := c.tree.Split(func(m chunkMeasure[M]) bool {
+		return m.count > index
+	})
+	if k := index - left.Measure().count; k > 0 && !right.IsEmpty() {
+		meas := c.meas()
+		l := right.PeekFirst()
+		left = left.AddLast(meas.leaf(l.values[:k:k]))
+		right = right.RemoveFirst().AddFirst(meas.leaf(l.values[k:]))
+	}
+	return c.with(left), c.with(right)
+}
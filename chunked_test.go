@@ -0,0 +1,133 @@
+package lazyfingertree
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+func checkChunked(t *testing.T, c Chunked[width[int, int], int, int], want []int) {
+	t.Helper()
+	failIfNot(t, c.Len() == len(want) && c.Measure() == len(want) && c.IsEmpty() == (len(want) == 0))
+	failIfNot(t, same(c.ToSlice(), want))
+	for i, v := range want {
+		failIfNot(t, c.Get(i) == v)
+	}
+	c.tree.Each(func(l leaf[int, int]) bool {
+		failIfNot(t, len(l.values) > 0 && len(l.values) <= c.chunkSize && l.measure == len(l.values))
+		return true
+	})
+}
+
+func TestChunked(t *testing.T) {
+	rng := rand.New(rand.NewSource(12))
+	for _, chunkSize := range []int{0, 1, 4, 16} {
+		want := []int{}
+		c := FromArrayChunked(newWidth[int](), want, chunkSize)
+		for i := 0; i < 400; i++ {
+			switch rng.Intn(7) {
+			case 0:
+				c = c.AddFirst(i)
+				want = append([]int{i}, want...)
+			case 1:
+				if len(want) > 0 {
+					c = c.RemoveFirst()
+					want = want[1:]
+				}
+			case 2:
+				if len(want) > 0 {
+					c = c.RemoveLast()
+					want = want[:len(want)-1]
+				}
+			case 3:
+				index := rng.Intn(len(want) + 1)
+				left, right := c.SplitAt(index)
+				checkChunked(t, left, want[:index])
+				checkChunked(t, right, want[index:])
+				c = left.Concat(right)
+			case 4:
+				index := rng.Intn(len(want) + 1)
+				left, right := c.Split(func(m int) bool { return m > index })
+				checkChunked(t, left, want[:index])
+				checkChunked(t, right, want[index:])
+				more := make([]int, rng.Intn(30))
+				for j := range more {
+					more[j] = -j
+				}
+				c = c.Concat(FromArrayChunked(newWidth[int](), more, chunkSize))
+				want = append(want, more...)
+			default:
+				c = c.AddLast(i)
+				want = append(want, i)
+			}
+			checkChunked(t, c, want)
+		}
+		if len(want) > 0 {
+			failIfNot(t, c.PeekFirst() == want[0] && c.PeekLast() == want[len(want)-1])
+		}
+	}
+}
+
+func benchmarkMemory(b *testing.B, build func([]int) any) {
+	nums := make([]int, 1000000)
+	for i := range nums {
+		nums[i] = i
+	}
+	var retained uint64
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		tree := build(nums)
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		retained += after.HeapAlloc - before.HeapAlloc
+		runtime.KeepAlive(tree)
+	}
+	b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+}
+
+func BenchmarkMemoryPlain(b *testing.B) {
+	benchmarkMemory(b, func(nums []int) any { return newTree(nums...) })
+}
+
+func BenchmarkMemoryChunked(b *testing.B) {
+	benchmarkMemory(b, func(nums []int) any { return FromArrayChunked(newWidth[int](), nums, 16) })
+}
+
+func BenchmarkEachPlain(b *testing.B) {
+	tree := newTree(make([]int, 1000000)...)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Each(func(int) bool { return true })
+	}
+}
+
+func BenchmarkEachChunked(b *testing.B) {
+	tree := FromArrayChunked(newWidth[int](), make([]int, 1000000), 16)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree.Each(func(int) bool { return true })
+	}
+}
+
+// Removing from an empty chunked tree panics like removing from an empty FingerTree
+func TestChunkedRemoveEmpty(t *testing.T) {
+	empty := FromArrayChunked(newWidth[int](), []int{}, 4)
+	for _, remove := range []func(){
+		func() { empty.RemoveFirst() },
+		func() { empty.RemoveLast() },
+		func() { FromArrayChunked(newWidth[int](), []int{1}, 4).RemoveFirst().RemoveLast() },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				failIfNot(t, errors.Is(err, ErrEmptyTree))
+			}()
+			remove()
+			t.Error("expected a panic")
+		}()
+	}
+	checkChunked(t, FromArrayChunked(newWidth[int](), []int{1}, 4).RemoveLast(), []int{})
+}
@@ -3,25 +3,28 @@ package lazyfingertree
 import (
 	"fmt"
 	"io"
+	"sync/atomic"
 )
 
 // A finger-tree which contains more than one element.
 type deepTree struct {
-	measured     bool
-	_measurement measurement
-	left         *digit
-	mid          fingerTree
-	right        *digit
+	cache    atomic.Pointer[deepCache] // nil until measured
+	measurer measurer
+	left     *digit
+	mid      fingerTree
+	right    *digit
+}
+
+// A deep tree's measure and size, which are computed when first needed because that
+// forces the mid tree. Trees are shared between goroutines so this is published
+// atomically and never changes.
+type deepCache struct {
+	value any
+	size  int
 }
 
 func newDeepTree(measurer measurer, left *digit, mid fingerTree, right *digit) *deepTree {
-	return &deepTree{
-		false,
-		measurement{measurer, measurer.Identity()},
-		left,
-		mid,
-		right,
-	}
+	return &deepTree{measurer: measurer, left: left, mid: mid, right: right}
 }
 
 func (d *deepTree) String() string {
@@ -53,15 +56,27 @@ func (d *deepTree) dumpDigits(w io.Writer, level int, dig *digit) {
 }
 
 func (d *deepTree) measurement() measurement {
-	if !d.measured {
-		meas := d._measurement.measurer
-		d._measurement.value = meas.Sum(
-			meas.Sum(d.left._measurement.value, d.mid.measurement().value),
-			d.right._measurement.value,
-		)
-		d.measured = true
+	return measurement{d.measurer, d.measured().value}
+}
+
+func (d *deepTree) size() int {
+	return d.measured().size
+}
+
+func (d *deepTree) measured() *deepCache {
+	c := d.cache.Load()
+	if c == nil {
+		meas := d.measurer
+		c = &deepCache{
+			meas.Sum(
+				meas.Sum(d.left._measurement.value, d.mid.measurement().value),
+				d.right._measurement.value,
+			),
+			d.left._size + d.mid.size() + d.right._size,
+		}
+		d.cache.Store(c)
 	}
-	return d._measurement
+	return c
 }
 
 func (d *deepTree) AddFirst(v any) fingerTree {
@@ -113,7 +128,7 @@ func (d *deepTree) RemoveFirst() fingerTree {
 	if d.left.len() > 1 {
 		return newDeepTree(meas, d.left.removeFirst(), d.mid, d.right)
 	} else if !isEmpty(d.mid) {
-		newMid := newDelayed(func() fingerTree { return d.mid.RemoveFirst() })
+		newMid := suspend(meas, "RemoveFirst", func() fingerTree { return d.mid.RemoveFirst() })
 		midFirst := d.mid.PeekFirst()
 		return newDeepTree(meas, asNode(midFirst).toDigit(), newMid, d.right)
 	} else if d.right.len() == 1 {
@@ -127,7 +142,7 @@ func (d *deepTree) RemoveLast() fingerTree {
 	if d.right.len() > 1 {
 		return newDeepTree(meas, d.left, d.mid, d.right.removeLast())
 	} else if !isEmpty(d.mid) {
-		newMid := newDelayed(func() fingerTree { return d.mid.RemoveLast() })
+		newMid := suspend(meas, "RemoveLast", func() fingerTree { return d.mid.RemoveLast() })
 		last := d.mid.PeekLast()
 		return newDeepTree(meas, d.left, newMid, asNode(last).toDigit())
 	} else if d.left.len() == 1 {
@@ -217,12 +232,35 @@ func (d *deepTree) splitTree(predicate predicate, initial any) (fingerTree, any,
 		fromArray(meas, right)
 }
 
+// Helper function to split the tree into 3 parts by position rather than by measure.
+// The middle value is the item holding the value at index.
+func (d *deepTree) splitIndex(index int) (fingerTree, any, fingerTree) {
+	meas := measurerFor(d)
+	if index < d.left._size {
+		left, mid, right := d.left.splitIndex(index)
+		return fromArray(meas, left), mid, deepLeft(meas, right, d.mid, d.right)
+	}
+	index -= d.left._size
+	midSize := d.mid.size()
+	if index < midSize {
+		mleft, mmid, mright := d.mid.splitIndex(index)
+		left, mid, right := asNode(mmid).toDigit().splitIndex(index - mleft.size())
+		return deepRight(meas, d.left, mleft, left),
+			mid,
+			deepLeft(meas, right, mright, d.right)
+	}
+	left, mid, right := d.right.splitIndex(index - midSize)
+	return deepRight(meas, d.left, d.mid, left),
+		mid,
+		fromArray(meas, right)
+}
+
 func deepLeft(meas measurer, left []any, mid fingerTree, right *digit) fingerTree {
 	if len(left) == 0 {
 		if isEmpty(mid) {
 			return fromArray(meas, right.items)
 		}
-		return newDelayed(func() fingerTree {
+		return suspend(meas, "Split", func() fingerTree {
 			return newDeepTree(meas,
 				asNode(mid.PeekFirst()).toDigit(),
 				mid.RemoveFirst(),
@@ -237,7 +275,7 @@ func deepRight(meas measurer, left *digit, mid fingerTree, right []any) fingerTr
 		if isEmpty(mid) {
 			return fromArray(meas, left.items)
 		}
-		return newDelayed(func() fingerTree {
+		return suspend(meas, "Split", func() fingerTree {
 			return newDeepTree(meas,
 				left,
 				mid.RemoveLast(),
@@ -267,16 +305,78 @@ func app3(t1 fingerTree, items []*node, t2 fingerTree) fingerTree {
 	}
 	d1, _ := t1.(*deepTree)
 	d2, _ := t2.(*deepTree)
-	return newDeepTree(
-		measurerFor(d1),
-		d1.left,
-		newDelayed(func() fingerTree {
-			return app3(
-				d1.mid,
-				nodes(measurerFor(d1), concat3(d1.right.items, items, d2.left.items)),
-				d2.mid)
-		}),
-		d2.right)
+	meas := measurerFor(d1)
+	middle := nodes(meas, concat3(d1.right.items, items, d2.left.items))
+	if isStrict(meas) {
+		return newDeepTree(meas, d1.left, app3(d1.mid, middle, d2.mid), d2.right)
+	}
+	mid := concatMids(meas, d1.mid, middle, d2.mid)
+	result := newDeepTree(meas, d1.left, mid, d2.right)
+	if mid.known {
+		// measure now so measuring the result does not force the join
+		result.measurement()
+	}
+	return result
+}
+
+// The most unforced Concat suspensions forcing one suspension can run. Concat pays
+// down older suspensions when a new one would exceed this, so no single operation
+// pays for thousands of deferred joins.
+const maxConcatDebt = 32
+
+// Suspend joining two mid trees around middle. If the mid trees' measures are known
+// without forcing them, the suspension knows its measure too.
+func concatMids(meas measurer, mid1 fingerTree, middle []*node, mid2 fingerTree) *delayed {
+	debt := 1 + concatDebt(mid1) + concatDebt(mid2)
+	if debt > maxConcatDebt {
+		mid1, mid2 = force(mid1), force(mid2)
+		debt = 1
+	}
+	mid := newDelayed("Concat", func() fingerTree {
+		return app3(mid1, middle, mid2)
+	})
+	mid.debt = debt
+	m1, ok1 := knownMeasurement(mid1)
+	m2, ok2 := knownMeasurement(mid2)
+	if ok1 && ok2 {
+		nm := newNodeMeasurer(meas)
+		m := m1.value
+		size := mid1.size()
+		for _, n := range middle {
+			m = meas.Sum(m, n._measurement.value)
+			size += n._size
+		}
+		mid.known = true
+		mid._measurement = measurement{nm, meas.Sum(m, m2.value)}
+		mid._size = size + mid2.size()
+	}
+	return mid
+}
+
+// Return the number of unforced Concat suspensions forcing tree would run.
+func concatDebt(tree fingerTree) int {
+	if d, ok := tree.(*delayed); ok && d.pending() {
+		return d.debt
+	}
+	return 0
+}
+
+// Return the tree's measurement if it is available without forcing anything.
+func knownMeasurement(tree fingerTree) (measurement, bool) {
+	switch t := tree.(type) {
+	case *delayed:
+		if !t.pending() {
+			return knownMeasurement(t.delayedTree)
+		}
+		return t._measurement, t.known
+	case *deepTree:
+		if t.cache.Load() == nil {
+			if _, ok := knownMeasurement(t.mid); !ok {
+				return measurement{}, false
+			}
+		}
+	}
+	return tree.measurement(), true
 }
 
 func appendAll[V any](result []any, slice []V) []any {
@@ -3,21 +3,181 @@ package lazyfingertree
 import (
 	"fmt"
 	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type fingerTreeFunc func() fingerTree
 
+// Trees are shared between goroutines, so forcing takes a lock. Once forced is set,
+// delayedTree never changes and is read without one.
 type delayed struct {
 	f           fingerTreeFunc
+	mu          sync.Mutex
+	forced      atomic.Bool
 	delayedTree fingerTree
+	op          string    // the operation that created the suspension
+	callers     []uintptr // the stack that created it, if DebugSuspensions is on
+	// Concat suspensions know their measure and size so measuring does not force them
+	known        bool
+	_measurement measurement
+	_size        int
+	debt         int // the number of unforced Concat suspensions forcing this one runs
 }
 
-func newDelayed(f fingerTreeFunc) *delayed {
-	tree := &delayed{f: f}
-	tree.delayedTree = tree
+// Set DebugSuspensions to true to record where each suspension is created, so a
+// panic while forcing one names the code that called the creating operation.
+// This costs a stack capture per suspension so it is meant for debugging.
+var DebugSuspensions = false
+
+// A SuspensionPanic wraps a panic raised while forcing a suspension, often by a
+// Measurer, with the operation that created the suspension. Forcing can happen long
+// after that operation, so the panic's own stack may point somewhere unrelated.
+type SuspensionPanic struct {
+	Value  any    // the original panic value
+	Op     string // the operation that created the suspension, like "Concat"
+	Caller string // the file and line that called Op, if DebugSuspensions was on
+}
+
+func (p *SuspensionPanic) Error() string {
+	where := ""
+	if p.Caller != "" {
+		where = " at " + p.Caller
+	}
+	return fmt.Sprintf("%v, panic forcing a suspension created by %s%s: %v", ErrFingerTree, p.Op, where, p.Value)
+}
+
+func (p *SuspensionPanic) Unwrap() []error {
+	if err, ok := p.Value.(error); ok {
+		return []error{ErrFingerTree, err}
+	}
+	return []error{ErrFingerTree}
+}
+
+func newDelayed(op string, f fingerTreeFunc) *delayed {
+	tree := &delayed{f: f, op: op}
+	if DebugSuspensions {
+		pcs := make([]uintptr, 32)
+		tree.callers = pcs[:runtime.Callers(3, pcs)]
+	}
 	return tree
 }
 
+var packagePrefix = reflect.TypeFor[delayed]().PkgPath() + "."
+
+// Return the first caller outside the package, or in its tests, that led to the suspension.
+func (f *delayed) caller() string {
+	if len(f.callers) == 0 {
+		return ""
+	}
+	frames := runtime.CallersFrames(f.callers)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		} else if !more {
+			return ""
+		}
+	}
+}
+
+// A strictMeasurer marks a tree as strict. Strict trees evaluate everything
+// immediately instead of creating suspensions. Mid trees inherit strictness
+// through their nodeMeasurers.
+type strictMeasurer struct {
+	measurer
+}
+
+func isStrict(m measurer) bool {
+	switch mm := m.(type) {
+	case strictMeasurer:
+		return true
+	case nodeMeasurer:
+		return mm.strict
+	}
+	return false
+}
+
+// Suspend f unless the tree is strict, in which case evaluate it now.
+func suspend(meas measurer, op string, f fingerTreeFunc) fingerTree {
+	if isStrict(meas) {
+		return f()
+	}
+	return newDelayed(op, f)
+}
+
+// Force up to budget suspensions down the spine of tree, returning whether any
+// unforced suspensions remain. Only mid trees can be suspended.
+func forceN(tree fingerTree, budget int) bool {
+	for {
+		switch t := tree.(type) {
+		case *delayed:
+			if t.pending() {
+				if budget <= 0 {
+					return true
+				}
+				t.force()
+				budget--
+			}
+			tree = t.delayedTree
+		case *deepTree:
+			tree = t.mid
+		default:
+			return false
+		}
+	}
+}
+
+// A LevelInfo describes one level of a tree's spine, see [FingerTree.LazyProfile].
+type LevelInfo struct {
+	Depth    int    // the spine depth, 0 for the root
+	Kind     string // "empty", "single", "deep", or "pending" for an unforced suspension
+	Op       string // the operation that created the level's suspension, if it has one
+	Forced   int    // how many forced suspensions lead to the level
+	Left     int    // the number of items in a deep level's left digit
+	Right    int    // the number of items in a deep level's right digit
+	Measured bool   // whether a deep level's measure is cached
+	Debt     int    // for a pending level, how many unforced suspensions forcing it runs
+}
+
+// Describe each level of the spine without forcing anything. An unforced suspension
+// hides the levels below it, so it is the last level.
+func lazyProfile(tree fingerTree) []LevelInfo {
+	var levels []LevelInfo
+	info := LevelInfo{}
+	for {
+		switch t := tree.(type) {
+		case *delayed:
+			info.Op = t.op
+			if t.pending() {
+				info.Kind = "pending"
+				// Concat suspensions hide the joins they were made from
+				info.Debt = max(1, t.debt)
+				return append(levels, info)
+			}
+			info.Forced++
+			tree = t.delayedTree
+			continue
+		case *deepTree:
+			info.Kind = "deep"
+			info.Left, info.Right = len(t.left.items), len(t.right.items)
+			info.Measured = t.cache.Load() != nil
+			levels = append(levels, info)
+			info = LevelInfo{Depth: info.Depth + 1}
+			tree = t.mid
+			continue
+		case *singleTree:
+			info.Kind = "single"
+		default:
+			info.Kind = "empty"
+		}
+		return append(levels, info)
+	}
+}
+
 func (f *delayed) String() string {
 	return fmt.Sprintf("delayed{%s}", f.force())
 }
@@ -26,21 +186,59 @@ func (f *delayed) Dump(w io.Writer, level int) {
 	f.force().Dump(w, level)
 }
 
+// Return whether the suspension has not been forced yet.
+func (f *delayed) pending() bool {
+	return !f.forced.Load()
+}
+
 func (f *delayed) force() fingerTree {
-	if f.delayedTree == f {
-		f.delayedTree = f.f()
+	if !f.forced.Load() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !f.forced.Load() {
+			f.delayedTree = f.evaluate()
+			f.forced.Store(true)
+		}
 	}
 	return f.delayedTree
 }
 
+// Run the suspension, wrapping any panic in a SuspensionPanic. Panics from nested
+// suspensions are already wrapped with the innermost, most specific, origin.
+func (f *delayed) evaluate() fingerTree {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*SuspensionPanic); !ok {
+				r = &SuspensionPanic{Value: r, Op: f.op, Caller: f.caller()}
+			}
+			panic(r)
+		}
+	}()
+	return f.f()
+}
+
 func (f *delayed) splitTree(predicate predicate, initial any) (fingerTree, any, fingerTree) {
 	return f.force().splitTree(predicate, initial)
 }
 
+func (f *delayed) splitIndex(index int) (fingerTree, any, fingerTree) {
+	return f.force().splitIndex(index)
+}
+
 func (f *delayed) measurement() measurement {
+	if f.known {
+		return f._measurement
+	}
 	return f.force().measurement()
 }
 
+func (f *delayed) size() int {
+	if f.known {
+		return f._size
+	}
+	return f.force().size()
+}
+
 func (f *delayed) AddFirst(value any) fingerTree {
 	return f.force().AddFirst(value)
 }
@@ -9,15 +9,18 @@ import (
 // this is not a FingerTree, it only shares some of the methods
 type digit struct {
 	_measurement measurement
+	_size        int
 	items        []any
 }
 
 func newDigit(measurer measurer, items []any) *digit {
 	m := measurer.Identity()
+	size := 0
 	for _, item := range items {
 		m = measurer.Sum(m, measurer.Measure(item))
+		size += sizeOf(item)
 	}
-	return &digit{measurement{measurer, m}, items}
+	return &digit{measurement{measurer, m}, size, items}
 }
 
 func (d *digit) String() string {
@@ -85,6 +88,19 @@ func (d *digit) dsplit(predicate predicate, initial any) ([]any, any, []any) {
 	return d.items[:i], item, d.items[i+1:]
 }
 
+// Split the digit around the item holding the value at index.
+func (d *digit) splitIndex(index int) ([]any, any, []any) {
+	i := 0
+	var item any
+	for i, item = range d.items {
+		if index < sizeOf(item) {
+			break
+		}
+		index -= sizeOf(item)
+	}
+	return d.items[:i], item, d.items[i+1:]
+}
+
 func (d *digit) Each(f iterFunc) bool {
 	for _, item := range d.items {
 		if !iterateEach(item, f) {
@@ -0,0 +1,72 @@
+package lazyfingertree
+
+import "fmt"
+
+var ErrBadEdit = fmt.Errorf("%w, bad edit", ErrFingerTree)
+
+// An Edit deletes Delete values starting at Pos and inserts Insert in their place.
+// Pos refers to a position in the original tree, not the partially edited one.
+type Edit[V any] struct {
+	Pos    int
+	Delete int
+	Insert []V
+}
+
+// Apply a batch of edits in a single left-to-right sweep.
+// The edits must be sorted by position and must not overlap, otherwise
+// this returns an ErrBadEdit error and no edits are applied.
+// The cost is O(k log n) for k edits plus the number of inserted values.
+func (t FingerTree[MS, V, M]) ApplyEdits(edits []Edit[V]) (FingerTree[MS, V, M], error) {
+	size := t.tree().size()
+	end := 0
+	for i, e := range edits {
+		if e.Delete < 0 {
+			return t, fmt.Errorf("%w: edit %d has negative delete count %d", ErrBadEdit, i, e.Delete)
+		} else if e.Pos < end {
+			return t, fmt.Errorf("%w: edit %d at %d overlaps or precedes the previous edit ending at %d", ErrBadEdit, i, e.Pos, end)
+		} else if e.Pos+e.Delete > size {
+			return t, fmt.Errorf("%w: edit %d from %d to %d is outside the tree of length %d", ErrBadEdit, i, e.Pos, e.Pos+e.Delete, size)
+		}
+		end = e.Pos + e.Delete
+	}
+	result := empty(t.tree())
+	rest := t.tree()
+	offset := 0
+	for _, e := range edits {
+		var left fingerTree
+		left, rest = splitAt(rest, e.Pos-offset)
+		result = appendTree(result.Concat(left), e.Insert)
+		_, rest = splitAt(rest, e.Delete)
+		offset = e.Pos + e.Delete
+	}
+	return wrapTree[MS, V, M](result.Concat(rest)), nil
+}
+
+// Drop values from the front until the tree holds at most max values, calling
+// onDrop (if it is not nil) for each dropped value in order. This is useful
+// for bounded buffers with eviction hooks.
+func (t FingerTree[MS, V, M]) CapFront(max int, onDrop func(V)) FingerTree[MS, V, M] {
+	size := t.tree().size()
+	if size <= max {
+		return t
+	}
+	dropped, rest := splitAt(t.tree(), size-max)
+	if onDrop != nil {
+		dropped.Each(wrapIter(func(v V) bool {
+			onDrop(v)
+			return true
+		}))
+	}
+	return wrapTree[MS, V, M](rest)
+}
+
+// Return the tree without the values from start up to end.
+// This panics unless 0 <= start <= end <= Len().
+func (t FingerTree[MS, V, M]) DeleteRange(start, end int) FingerTree[MS, V, M] {
+	if start < 0 || start > end || end > t.tree().size() {
+		panic(fmt.Errorf("%w: range %d-%d in tree of length %d", ErrOutOfRange, start, end, t.tree().size()))
+	}
+	left, rest := splitAt(t.tree(), start)
+	_, right := splitAt(rest, end-start)
+	return wrapTree[MS, V, M](normalizeEmpty(left.Concat(right), measurerFor(t.tree())))
+}
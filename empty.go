@@ -24,6 +24,10 @@ func (e *emptyTree) measurement() measurement {
 	return e._measurement
 }
 
+func (e *emptyTree) size() int {
+	return 0
+}
+
 func (e *emptyTree) AddFirst(value any) fingerTree {
 	return newSingleTree(measurerFor(e), value)
 }
@@ -61,6 +65,11 @@ func (e *emptyTree) splitTree(pred predicate, initial any) (fingerTree, any, fin
 	return e, nil, e
 }
 
+// never called but required for the interface
+func (e *emptyTree) splitIndex(index int) (fingerTree, any, fingerTree) {
+	return e, nil, e
+}
+
 func (d *emptyTree) ToSlice() []any {
 	return []any{}
 }
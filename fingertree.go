@@ -17,6 +17,8 @@ var ErrBadMeasurer = fmt.Errorf("%w, bad measurer", ErrFingerTree)
 
 var ErrExpectedNode = fmt.Errorf("%w, expected a node", ErrFingerTree)
 
+var ErrZeroTree = fmt.Errorf("%w, zero tree has no measurer, create trees with a constructor like FromArray or NewBuilder", ErrFingerTree)
+
 type predicate func(measure any) bool
 
 type iterFunc func(value any) bool
@@ -51,6 +53,21 @@ type measurer interface {
 	Sum(a any, b any) any
 }
 
+// The measurer of zero trees
+type zeroMeasurer struct{}
+
+func (m zeroMeasurer) Identity() any {
+	panic(fmt.Errorf("%w: cannot measure", ErrZeroTree))
+}
+
+func (m zeroMeasurer) Measure(value any) any {
+	panic(fmt.Errorf("%w: cannot measure", ErrZeroTree))
+}
+
+func (m zeroMeasurer) Sum(a any, b any) any {
+	panic(fmt.Errorf("%w: cannot measure", ErrZeroTree))
+}
+
 type measurement struct {
 	measurer measurer
 	value    any
@@ -78,7 +95,9 @@ type fingerTree interface {
 	Each(f iterFunc) bool
 	EachReverse(f iterFunc) bool
 	measurement() measurement
+	size() int
 	splitTree(predicate predicate, initial any) (fingerTree, any, fingerTree)
+	splitIndex(index int) (fingerTree, any, fingerTree)
 	fmt.Stringer
 	Dump(w io.Writer, level int)
 }
@@ -88,12 +107,41 @@ func isEmpty(tree fingerTree) bool {
 	return ok
 }
 
+// Return tree, or a fresh empty tree using meas if tree is nil or empty. Operations
+// that can remove every value use this so they always return a usable empty tree.
+func normalizeEmpty(tree fingerTree, meas measurer) fingerTree {
+	if tree == nil || isEmpty(tree) {
+		return newEmptyTree(meas)
+	}
+	return tree
+}
+
 func isSingle(tree fingerTree) bool {
 	_, ok := tree.(*singleTree)
 	return ok
 }
 
+// The number of levels in the tree's spine
+func spineDepth(tree fingerTree) int {
+	depth := 0
+	for {
+		switch t := force(tree).(type) {
+		case *deepTree:
+			depth++
+			tree = t.mid
+		case *singleTree:
+			return depth + 1
+		default:
+			return depth
+		}
+	}
+}
+
 func measurerFor(tree fingerTree) measurer {
+	if d, ok := tree.(*deepTree); ok {
+		// don't compute the measurement, that would force the mid tree
+		return d.measurer
+	}
 	return tree.measurement().measurer
 }
 
@@ -119,6 +167,22 @@ func dropUntil(tree fingerTree, f predicate) fingerTree {
 	return rest
 }
 
+// Split a tree so the left tree holds the first index values. A split inside the left
+// digit does not need the tree's size, so it leaves the mid tree suspended.
+func splitAt(tree fingerTree, index int) (fingerTree, fingerTree) {
+	if index <= 0 {
+		return empty(tree), tree
+	} else if d, ok := force(tree).(*deepTree); ok && index < d.left.len() {
+		meas := measurerFor(d)
+		return fromArray(meas, d.left.items[:index]),
+			newDeepTree(meas, d.left.slice(index, d.left.len()), d.mid, d.right)
+	} else if index >= tree.size() {
+		return tree, empty(tree)
+	}
+	left, mid, right := tree.splitIndex(index)
+	return left, right.AddFirst(mid)
+}
+
 // Construct a fingertree from an array.
 func fromArray(measurer measurer, values []any) fingerTree {
 	return prependTree(newEmptyTree(measurer), values)
@@ -142,6 +206,14 @@ func appendTree[V any](tree fingerTree, values []V) fingerTree {
 	return tree
 }
 
+// The number of values an item holds, nodes hold all of their leaves
+func sizeOf(item any) int {
+	if n, ok := item.(*node); ok {
+		return n._size
+	}
+	return 1
+}
+
 func iterateEach(item any, f iterFunc) bool {
 	if n, ok := item.(*node); ok {
 		return n.Each(f)
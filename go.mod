@@ -1,3 +1,3 @@
 module github.com/leisure-tools/lazyfingertree
 
-go 1.19
+go 1.23
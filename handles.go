@@ -0,0 +1,198 @@
+package lazyfingertree
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"sync/atomic"
+)
+
+// A HandleTree is a persistent sequence whose values carry stable handles, like
+// bookmarks into a document, that keep referring to the same value across insertions
+// and deletions elsewhere. Each value has a fixed order label that sorts in sequence
+// order, so the measure tracks the last label along with the tree's own measure and
+// resolving a handle takes one descent.
+type HandleTree[MS Measurer[V, M], V, M any] struct {
+	tree FingerTree[handleMeasurer[MS, V, M], handled[V], handleMeasure[M]]
+}
+
+// A ValueHandle refers to one value in a HandleTree, see [Resolve]. The zero ValueHandle
+// refers to nothing.
+type ValueHandle struct {
+	id    uint64
+	label []uint64
+}
+
+type handled[V any] struct {
+	value V
+	id    uint64
+	label []uint64
+}
+
+type handleMeasure[M any] struct {
+	inner M
+	last  []uint64 // the largest label, nil if there are no values
+}
+
+type handleMeasurer[MS Measurer[V, M], V, M any] struct {
+	inner MS
+}
+
+func (m handleMeasurer[MS, V, M]) Identity() handleMeasure[M] {
+	return handleMeasure[M]{inner: m.inner.Identity()}
+}
+
+func (m handleMeasurer[MS, V, M]) Measure(h handled[V]) handleMeasure[M] {
+	return handleMeasure[M]{m.inner.Measure(h.value), h.label}
+}
+
+func (m handleMeasurer[MS, V, M]) Sum(a handleMeasure[M], b handleMeasure[M]) handleMeasure[M] {
+	last := b.last
+	if last == nil {
+		last = a.last
+	}
+	return handleMeasure[M]{m.inner.Sum(a.inner, b.inner), last}
+}
+
+// Handle ids are unique across all trees so a handle never matches a value from a
+// different edit history
+var handleIds atomic.Uint64
+
+// The gap left between labels for appended and prepended values, which leaves room
+// to insert between them before labels need another word
+const labelStep = 1 << 32
+
+// Return a tree of t's values, each with a new handle.
+func WithHandles[MS Measurer[V, M], V, M any](t FingerTree[MS, V, M]) HandleTree[MS, V, M] {
+	b := NewBuilder(handleMeasurer[MS, V, M]{t.measurer()})
+	label := uint64(0)
+	t.Each(func(v V) bool {
+		label += labelStep
+		b.Add(handled[V]{v, handleIds.Add(1), []uint64{label}})
+		return true
+	})
+	return HandleTree[MS, V, M]{b.Tree()}
+}
+
+// Return a label that sorts after lo and before hi. Labels compare like strings of
+// words and never end in zero, so there is always room between two of them. Without
+// hi, the label is a step past lo.
+func labelBetween(lo, hi []uint64, hasHi bool) []uint64 {
+	var label []uint64
+	for i := 0; ; i++ {
+		l := uint64(0)
+		if i < len(lo) {
+			l = lo[i]
+		}
+		if !hasHi {
+			if l <= math.MaxUint64-labelStep {
+				return append(label, l+labelStep)
+			}
+			label = append(label, l)
+			continue
+		}
+		// label matches hi so far, so hi has more words because lo < hi
+		h := hi[i]
+		switch {
+		case i >= len(lo) && h > labelStep:
+			// label matches all of lo, so stepping back from hi is enough
+			return append(label, h-labelStep)
+		case h-l > 1:
+			return append(label, l+(h-l)/2)
+		}
+		label = append(label, l)
+		if l < h {
+			hasHi = false
+		}
+	}
+}
+
+// Return the number of values.
+func (s HandleTree[MS, V, M]) Len() int {
+	return s.tree.Len()
+}
+
+// Return the measure of the values.
+func (s HandleTree[MS, V, M]) Measure() M {
+	return s.tree.Measure().inner
+}
+
+// Return the value at index. This panics if index is out of range.
+func (s HandleTree[MS, V, M]) Get(index int) V {
+	return s.tree.Get(index).value
+}
+
+// Return a handle to the value at index. This panics if index is out of range.
+func (s HandleTree[MS, V, M]) HandleOf(index int) ValueHandle {
+	h := s.tree.Get(index)
+	return ValueHandle{h.id, h.label}
+}
+
+// Return the values in order.
+func (s HandleTree[MS, V, M]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		s.tree.Each(func(h handled[V]) bool {
+			return yield(h.value)
+		})
+	}
+}
+
+// Return a tree with value inserted at index and the new value's handle.
+// This panics unless 0 <= index <= Len().
+func (s HandleTree[MS, V, M]) Insert(index int, value V) (HandleTree[MS, V, M], ValueHandle) {
+	if index < 0 || index > s.Len() {
+		panic(fmt.Errorf("%w: %d in tree of length %d", ErrOutOfRange, index, s.Len()))
+	}
+	left, right := s.split(index)
+	var hi []uint64
+	if !right.IsEmpty() {
+		hi = right.PeekFirst().label
+	}
+	h := handled[V]{value, handleIds.Add(1), labelBetween(left.Measure().last, hi, hi != nil)}
+	return HandleTree[MS, V, M]{left.AddLast(h).Concat(right)}, ValueHandle{h.id, h.label}
+}
+
+// Return a tree with the value at index replaced by value, which keeps its handle.
+// This panics if index is out of range.
+func (s HandleTree[MS, V, M]) Set(index int, value V) HandleTree[MS, V, M] {
+	h := s.tree.Get(index)
+	h.value = value
+	left, right := s.split(index)
+	return HandleTree[MS, V, M]{left.AddLast(h).Concat(right.RemoveFirst())}
+}
+
+// Return a tree without the value at index. Its handles no longer resolve in the
+// result. This panics if index is out of range.
+func (s HandleTree[MS, V, M]) Delete(index int) HandleTree[MS, V, M] {
+	if index < 0 || index >= s.Len() {
+		panic(fmt.Errorf("%w: %d in tree of length %d", ErrOutOfRange, index, s.Len()))
+	}
+	left, right := s.split(index)
+	return HandleTree[MS, V, M]{left.Concat(right.RemoveFirst())}
+}
+
+func (s HandleTree[MS, V, M]) split(index int) (FingerTree[handleMeasurer[MS, V, M], handled[V], handleMeasure[M]], FingerTree[handleMeasurer[MS, V, M], handled[V], handleMeasure[M]]) {
+	left, right := splitAt(s.tree.tree(), index)
+	return wrapTree[handleMeasurer[MS, V, M], handled[V], handleMeasure[M]](left),
+		wrapTree[handleMeasurer[MS, V, M], handled[V], handleMeasure[M]](right)
+}
+
+// Return the current index and value of the value h refers to, in one descent.
+// This returns false if the value has been deleted or h is from another tree.
+func Resolve[MS Measurer[V, M], V, M any](s HandleTree[MS, V, M], h ValueHandle) (int, V, bool) {
+	if h.id == 0 || s.tree.IsEmpty() {
+		return -1, null[V](), false
+	}
+	pred := wrapPredicate(func(m handleMeasure[M]) bool {
+		return m.last != nil && slices.Compare(m.last, h.label) >= 0
+	})
+	if !pred(s.tree.tree().measurement().value) {
+		return -1, null[V](), false
+	}
+	loc := locate(s.tree.tree(), pred, s.tree.measurer().Identity())
+	if v := loc.value.(handled[V]); v.id == h.id {
+		return loc.index, v.value, true
+	}
+	return -1, null[V](), false
+}
@@ -0,0 +1,96 @@
+package lazyfingertree
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestHandles(t *testing.T) {
+	doc := WithHandles(FromArray(sumValues(0), []int{10, 20, 30}))
+	failIfNot(t, doc.Len() == 3 && doc.Measure() == 60)
+	bookmark := doc.HandleOf(1)
+	edited, added := doc.Insert(0, 5)
+	edited = edited.Delete(3).Set(2, 21)
+	failIfNot(t, slices.Equal(slices.Collect(edited.Values()), []int{5, 10, 21}) && edited.Measure() == 36)
+	i, v, ok := Resolve(edited, bookmark)
+	failIfNot(t, ok && i == 2 && v == 21)
+	i, v, ok = Resolve(edited, added)
+	failIfNot(t, ok && i == 0 && v == 5)
+	// the original is unchanged and does not have the new value
+	i, v, ok = Resolve(doc, bookmark)
+	failIfNot(t, ok && i == 1 && v == 20)
+	_, _, ok = Resolve(doc, added)
+	failIfNot(t, !ok)
+	_, _, ok = Resolve(edited.Delete(2), bookmark)
+	failIfNot(t, !ok)
+	// a value inserted where a deleted one was does not take over its handle
+	reinserted, _ := edited.Delete(2).Insert(2, 21)
+	_, _, ok = Resolve(reinserted, bookmark)
+	failIfNot(t, !ok)
+	_, _, ok = Resolve(edited, ValueHandle{})
+	failIfNot(t, !ok)
+	empty := WithHandles(FromArray(sumValues(0), []int{}))
+	_, _, ok = Resolve(empty, bookmark)
+	failIfNot(t, !ok && empty.Len() == 0)
+}
+
+func TestLabelBetween(t *testing.T) {
+	lo := []uint64{}
+	hi := []uint64{labelStep}
+	// repeatedly inserting at the same place squeezes labels together
+	for i := 0; i < 500; i++ {
+		label := labelBetween(lo, hi, true)
+		failIfNot(t, slices.Compare(lo, label) < 0 && slices.Compare(label, hi) < 0 && label[len(label)-1] != 0)
+		if i%2 == 0 {
+			lo = label
+		} else {
+			hi = label
+		}
+	}
+	appended := labelBetween([]uint64{math.MaxUint64 - 1}, nil, false)
+	failIfNot(t, slices.Compare(appended, []uint64{math.MaxUint64 - 1}) > 0)
+}
+
+func TestHandlesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	doc := WithHandles(FromArray(sumValues(0), []int{}))
+	var values []int
+	var handles []ValueHandle
+	var deleted []ValueHandle
+	for i := 0; i < 2000; i++ {
+		switch op := r.Intn(10); {
+		case op < 6 || len(values) == 0:
+			index := r.Intn(len(values) + 1)
+			// favor the front so labels get squeezed
+			if op < 2 {
+				index = 0
+			}
+			var h ValueHandle
+			doc, h = doc.Insert(index, i)
+			values = slices.Insert(values, index, i)
+			handles = slices.Insert(handles, index, h)
+		case op < 8:
+			index := r.Intn(len(values))
+			doc = doc.Delete(index)
+			deleted = append(deleted, handles[index])
+			values = slices.Delete(values, index, index+1)
+			handles = slices.Delete(handles, index, index+1)
+		default:
+			index := r.Intn(len(values))
+			doc = doc.Set(index, -i)
+			values[index] = -i
+		}
+	}
+	failIfNot(t, slices.Equal(slices.Collect(doc.Values()), values))
+	for index, h := range handles {
+		i, v, ok := Resolve(doc, h)
+		failIfNot(t, ok && i == index && v == values[index])
+		failIfNot(t, doc.HandleOf(index).id == h.id)
+	}
+	for _, h := range deleted {
+		_, _, ok := Resolve(doc, h)
+		failIfNot(t, !ok)
+	}
+}
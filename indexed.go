@@ -0,0 +1,44 @@
+package lazyfingertree
+
+import "fmt"
+
+const indexedBlockSize = 64
+
+// An IndexedView provides random access to a tree's values. It caches a block of
+// values around the last access so loops over nearby indexes mostly avoid
+// descending the tree.
+type IndexedView[V any] struct {
+	tree  fingerTree
+	start int
+	block []V
+}
+
+// Return an indexed view of the tree.
+func (t FingerTree[MS, V, M]) AsIndexed() *IndexedView[V] {
+	return &IndexedView[V]{tree: t.tree()}
+}
+
+// Return the number of values in the view.
+func (v *IndexedView[V]) Len() int {
+	return v.tree.size()
+}
+
+// Return the value at index. This panics if index is out of range.
+func (v *IndexedView[V]) At(index int) V {
+	if index >= v.start && index < v.start+len(v.block) {
+		return v.block[index-v.start]
+	} else if index < 0 || index >= v.tree.size() {
+		panic(fmt.Errorf("%w: %d in view of length %d", ErrOutOfRange, index, v.tree.size()))
+	}
+	v.start = index - index%indexedBlockSize
+	_, rest := splitAt(v.tree, v.start)
+	if v.block == nil {
+		v.block = make([]V, 0, indexedBlockSize)
+	}
+	v.block = v.block[:0]
+	rest.Each(func(value any) bool {
+		v.block = append(v.block, value.(V))
+		return len(v.block) < indexedBlockSize
+	})
+	return v.block[index-v.start]
+}
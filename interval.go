@@ -0,0 +1,108 @@
+package lazyfingertree
+
+import "sort"
+
+// An Interval is the closed range of keys from Low to High.
+type Interval[K any] struct {
+	Low, High K
+}
+
+// An IntervalMeasurer measures intervals in a tree sorted by low endpoint.
+type IntervalMeasurer[K any] struct {
+	// Return a negative number if a < b, zero if a == b, or a positive number if a > b
+	Compare func(a, b K) int
+}
+
+// An IntervalMeasure holds the largest low endpoint and the largest high endpoint in a
+// sequence of intervals. Valid is false for empty sequences.
+type IntervalMeasure[K any] struct {
+	Low, High K
+	Valid     bool
+}
+
+// An IntervalTree is a tree of intervals sorted by low endpoint
+type IntervalTree[K any] = FingerTree[IntervalMeasurer[K], Interval[K], IntervalMeasure[K]]
+
+func (m IntervalMeasurer[K]) Identity() IntervalMeasure[K] {
+	return IntervalMeasure[K]{}
+}
+
+func (m IntervalMeasurer[K]) Measure(value Interval[K]) IntervalMeasure[K] {
+	return IntervalMeasure[K]{value.Low, value.High, true}
+}
+
+func (m IntervalMeasurer[K]) Sum(a IntervalMeasure[K], b IntervalMeasure[K]) IntervalMeasure[K] {
+	if !a.Valid {
+		return b
+	} else if !b.Valid {
+		return a
+	}
+	result := a
+	if m.Compare(b.Low, a.Low) > 0 {
+		result.Low = b.Low
+	}
+	if m.Compare(b.High, a.High) > 0 {
+		result.High = b.High
+	}
+	return result
+}
+
+// Insert a batch of intervals into a tree sorted by low endpoint. The batch is sorted
+// and spliced into the tree run by run, so runs of the batch that fall between the same
+// two intervals of the tree cost one split. Intervals with equal low endpoints keep
+// their order, with the tree's before the batch's.
+func AddIntervals[K any](t IntervalTree[K], intervals []Interval[K]) IntervalTree[K] {
+	meas := t.measurer()
+	batch := Dup(intervals)
+	sort.SliceStable(batch, func(i, j int) bool {
+		return meas.Compare(batch[i].Low, batch[j].Low) < 0
+	})
+	result := empty(t.tree())
+	rest := t.tree()
+	for i := 0; i < len(batch); {
+		low := batch[i].Low
+		left, right := rest.Split(wrapPredicate(func(m IntervalMeasure[K]) bool {
+			return m.Valid && meas.Compare(m.Low, low) > 0
+		}))
+		result = result.Concat(left)
+		rest = right
+		j := i + 1
+		if isEmpty(rest) {
+			j = len(batch)
+		} else {
+			next := rest.PeekFirst().(Interval[K]).Low
+			for j < len(batch) && meas.Compare(batch[j].Low, next) < 0 {
+				j++
+			}
+		}
+		result = result.Concat(buildTree(measurerFor(t.tree()), appendAll(make([]any, 0, j-i), batch[i:j])))
+		i = j
+	}
+	return wrapTree[IntervalMeasurer[K], Interval[K], IntervalMeasure[K]](result.Concat(rest))
+}
+
+// Merge the intervals in a tree sorted by low endpoint that overlap or touch into
+// maximal disjoint intervals, which stay sorted by low endpoint.
+func CoalesceIntervals[K any](t IntervalTree[K]) IntervalTree[K] {
+	meas := t.measurer()
+	builder := newBuilder[IntervalMeasurer[K], Interval[K], IntervalMeasure[K]](measurerFor(t.tree()))
+	var current Interval[K]
+	started := false
+	t.Each(func(i Interval[K]) bool {
+		if !started {
+			current, started = i, true
+		} else if meas.Compare(i.Low, current.High) <= 0 {
+			if meas.Compare(i.High, current.High) > 0 {
+				current.High = i.High
+			}
+		} else {
+			builder.Add(current)
+			current = i
+		}
+		return true
+	})
+	if started {
+		builder.Add(current)
+	}
+	return builder.Tree()
+}
@@ -0,0 +1,61 @@
+package lazyfingertree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestIntervals(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	randomIntervals := func(n int) []Interval[int] {
+		result := make([]Interval[int], n)
+		for i := range result {
+			low := r.Intn(1000)
+			result[i] = Interval[int]{low, low + r.Intn(20)}
+		}
+		return result
+	}
+	meas := IntervalMeasurer[int]{compareInts}
+	initial := randomIntervals(200)
+	sort.SliceStable(initial, func(i, j int) bool { return initial[i].Low < initial[j].Low })
+	tree := FromArray(meas, initial)
+	batch := randomIntervals(300)
+	tree = AddIntervals(tree, batch)
+	all := append(Dup(initial), batch...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Low < all[j].Low })
+	values := tree.ToSlice()
+	failIfNot(t, len(values) == len(all))
+	maxHigh := 0
+	for i, v := range values {
+		failIfNot(t, v.Low == all[i].Low)
+		if v.High > maxHigh {
+			maxHigh = v.High
+		}
+	}
+	failIfNot(t, tree.Measure().High == maxHigh && tree.Measure().Low == all[len(all)-1].Low)
+	failIfErrNow(t, MeasuresConsistent(tree))
+	coalesced := CoalesceIntervals(tree)
+	covered := make([]bool, 1100)
+	for _, i := range all {
+		for k := i.Low; k <= i.High; k++ {
+			covered[k] = true
+		}
+	}
+	prev := Interval[int]{-10, -10}
+	for _, i := range coalesced.ToSlice() {
+		failIfNot(t, i.Low > prev.High)
+		for k := prev.High + 1; k < i.Low && k >= 0; k++ {
+			failIfNot(t, !covered[k])
+		}
+		for k := i.Low; k <= i.High; k++ {
+			failIfNot(t, covered[k])
+		}
+		prev = i
+	}
+	failIfNot(t, coalesced.Measure().High == maxHigh)
+	touching := CoalesceIntervals(FromArray(meas, []Interval[int]{{1, 3}, {3, 5}, {6, 7}}))
+	failIfNot(t, same(touching.ToSlice(), []Interval[int]{{1, 5}, {6, 7}}))
+	failIfNot(t, same(AddIntervals(FromArray(meas, []Interval[int]{}), []Interval[int]{{5, 6}, {1, 2}}).ToSlice(),
+		[]Interval[int]{{1, 2}, {5, 6}}))
+}
@@ -0,0 +1,334 @@
+package lazyfingertree
+
+import (
+	"hash"
+	"iter"
+)
+
+// Iterate through the tree, passing each value along with up to k of the values
+// that follow it. Near the end of the tree, ahead holds fewer values. The ahead
+// slice is reused between calls so don't retain it. Returning false stops iteration.
+func (t FingerTree[MS, V, M]) EachWithLookahead(k int, iter func(cur V, ahead []V) bool) {
+	if k < 0 {
+		k = 0
+	}
+	buf := make([]V, 0, k+1)
+	if !t.tree().Each(wrapIter(func(v V) bool {
+		buf = append(buf, v)
+		if len(buf) <= k {
+			return true
+		}
+		if !iter(buf[0], buf[1:]) {
+			return false
+		}
+		buf = buf[:copy(buf, buf[1:])]
+		return true
+	})) {
+		return
+	}
+	for len(buf) > 0 {
+		if !iter(buf[0], buf[1:]) {
+			return
+		}
+		buf = buf[:copy(buf, buf[1:])]
+	}
+}
+
+// A cursor pulls values out of a tree one at a time, forcing suspensions
+// only when it reaches them.
+type cursor struct {
+	stack []any
+}
+
+func newCursor(tree fingerTree) *cursor {
+	return &cursor{[]any{tree}}
+}
+
+func (c *cursor) push(items []any) {
+	for i := len(items) - 1; i >= 0; i-- {
+		c.stack = append(c.stack, items[i])
+	}
+}
+
+func (c *cursor) next() (any, bool) {
+	for len(c.stack) > 0 {
+		top := c.stack[len(c.stack)-1]
+		c.stack = c.stack[:len(c.stack)-1]
+		switch item := top.(type) {
+		case *delayed:
+			c.stack = append(c.stack, item.force())
+		case *emptyTree:
+		case *singleTree:
+			c.stack = append(c.stack, item.value)
+		case *deepTree:
+			c.stack = append(c.stack, item.right, item.mid, item.left)
+		case *digit:
+			c.push(item.items)
+		case *node:
+			c.push(item.children)
+		default:
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// Return whether next has a value to return. This only forces suspensions when
+// nothing else is left, since digits, nodes, and values are never empty.
+func (c *cursor) more() bool {
+	for _, item := range c.stack {
+		switch item.(type) {
+		case *emptyTree, *delayed:
+		default:
+			return true
+		}
+	}
+	for len(c.stack) > 0 {
+		switch item := c.stack[len(c.stack)-1].(type) {
+		case *delayed:
+			c.stack[len(c.stack)-1] = item.force()
+		case *emptyTree:
+			c.stack = c.stack[:len(c.stack)-1]
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Return a function that yields the tree's values in order, one per call, and false
+// once they are exhausted. This makes it easy to step through several trees together.
+func (t FingerTree[MS, V, M]) Iterator() func() (V, bool) {
+	c := newCursor(t.tree())
+	return func() (V, bool) {
+		if v, ok := c.next(); ok {
+			return v.(V), true
+		}
+		return null[V](), false
+	}
+}
+
+// Feed the values into h in order with writeValue and return the digest, for change
+// detection. Trees with the same values in the same order have the same hash.
+func (t FingerTree[MS, V, M]) HashWith(h hash.Hash, writeValue func(hash.Hash, V)) []byte {
+	t.Each(func(v V) bool {
+		writeValue(h, v)
+		return true
+	})
+	return h.Sum(nil)
+}
+
+// Fold f over the tree while f returns true. This returns the last accumulator and
+// whether the whole tree was consumed. Values after the one where f returns false are
+// not visited.
+func ReduceWhile[MS Measurer[V, M], V, M, A any](t FingerTree[MS, V, M], init A, f func(A, V) (A, bool)) (A, bool) {
+	acc := init
+	complete := t.tree().Each(wrapIter(func(v V) bool {
+		var more bool
+		acc, more = f(acc, v)
+		return more
+	}))
+	return acc, complete
+}
+
+// Fold f over the tree, passing each value's index and the measure of the values up
+// to and including it.
+func FoldIndexed[MS Measurer[V, M], V, M, A any](t FingerTree[MS, V, M], init A, f func(acc A, index int, v V, prefix M) A) A {
+	meas := t.measurer()
+	acc, index, prefix := init, 0, meas.Identity()
+	t.Each(func(v V) bool {
+		prefix = meas.Sum(prefix, meas.Measure(v))
+		acc = f(acc, index, v, prefix)
+		index++
+		return true
+	})
+	return acc
+}
+
+// Map each value into a monoid with project and combine the results from left to
+// right, for aggregates the tree is not measured by. Combine must be associative with
+// identity as its identity. This combines each node's values before combining the
+// nodes, so it equals a left fold but works a node at a time. Returns identity if the
+// tree is empty.
+func FoldMap[MS Measurer[V, M], V, M, A any](t FingerTree[MS, V, M], identity A, project func(V) A, combine func(A, A) A) A {
+	var foldItem func(item any) A
+	foldItems := func(items []any) A {
+		acc := foldItem(items[0])
+		for _, item := range items[1:] {
+			acc = combine(acc, foldItem(item))
+		}
+		return acc
+	}
+	foldItem = func(item any) A {
+		if n, ok := item.(*node); ok {
+			return foldItems(n.children)
+		}
+		return project(item.(V))
+	}
+	var foldTree func(tree fingerTree) A
+	foldTree = func(tree fingerTree) A {
+		switch tr := force(tree).(type) {
+		case *singleTree:
+			return foldItem(tr.value)
+		case *deepTree:
+			acc := foldItems(tr.left.items)
+			if !isEmpty(tr.mid) {
+				acc = combine(acc, foldTree(tr.mid))
+			}
+			return combine(acc, foldItems(tr.right.items))
+		}
+		return identity
+	}
+	return foldTree(t.tree())
+}
+
+// Iterate through the tree in batches of up to batchSize values, stopping when fn
+// returns false. The batch slice is reused between calls so don't retain it.
+func (t FingerTree[MS, V, M]) EachBatch(batchSize int, fn func([]V) bool) {
+	t.EachChunked(batchSize, fn)
+}
+
+// Iterate through the tree in order, handing f batches of up to maxBatch values.
+// This gathers values straight from the tree's nodes, avoiding the per-value call
+// overhead of Each, so it suits bulk copies and tight loops over the batch. The batch
+// slice is reused between calls so f must not retain it. Returning false stops
+// iteration without delivering any more batches.
+func (t FingerTree[MS, V, M]) EachChunked(maxBatch int, f func(batch []V) bool) {
+	c := &chunker[V]{batch: make([]V, 0, max(maxBatch, 1)), f: f}
+	if c.tree(t.tree()) && len(c.batch) > 0 {
+		f(c.batch)
+	}
+}
+
+type chunker[V any] struct {
+	batch []V
+	f     func([]V) bool
+}
+
+func (c *chunker[V]) tree(tree fingerTree) bool {
+	switch tr := force(tree).(type) {
+	case *singleTree:
+		return c.items([]any{tr.value})
+	case *deepTree:
+		return c.items(tr.left.items) && c.tree(tr.mid) && c.items(tr.right.items)
+	}
+	return true
+}
+
+func (c *chunker[V]) items(items []any) bool {
+	for _, item := range items {
+		if n, ok := item.(*node); ok {
+			if !c.items(n.children) {
+				return false
+			}
+			continue
+		}
+		c.batch = append(c.batch, item.(V))
+		if len(c.batch) == cap(c.batch) {
+			if !c.f(c.batch) {
+				return false
+			}
+			c.batch = c.batch[:0]
+		}
+	}
+	return true
+}
+
+// Return a sequence of the accumulator states from folding f over the tree.
+// Each value in the tree produces one state, the initial one is not included.
+// The tree is only traversed as far as the sequence is consumed.
+func FoldStates[MS Measurer[V, M], V, M, A any](t FingerTree[MS, V, M], init A, f func(A, V) A) iter.Seq[A] {
+	return func(yield func(A) bool) {
+		acc := init
+		t.Each(func(v V) bool {
+			acc = f(acc, v)
+			return yield(acc)
+		})
+	}
+}
+
+// Return a sequence of every pair of a value from a and a value from b, in order by
+// a's values and then b's, without building the product. Each pass through b
+// traverses it again and breaking out of the loop stops the traversal.
+func CrossProduct[MS Measurer[V, M], V, M any](a, b FingerTree[MS, V, M]) iter.Seq2[V, V] {
+	return func(yield func(V, V) bool) {
+		a.Each(func(va V) bool {
+			more := true
+			b.Each(func(vb V) bool {
+				more = yield(va, vb)
+				return more
+			})
+			return more
+		})
+	}
+}
+
+// Iterate through the values whose subtrees keepSubtree accepts. This consults
+// keepSubtree on the cached measure of every subtree, node, and digit before
+// descending into it, and on the measure of each value before calling iter, so it
+// can skip large regions without visiting them. Returning false stops iteration.
+// keepSubtree must be downward consistent: if it rejects a measure it must reject
+// the measures of every part of it, as "max >= P" does for a max measure, or
+// matching values will be skipped.
+func (t FingerTree[MS, V, M]) EachPruned(keepSubtree func(M) bool, iter IterFunc[V]) {
+	eachPruned(measurerFor(t.tree()), t.tree(), wrapPredicate(Predicate[M](keepSubtree)), wrapIter(iter))
+}
+
+// Iterate through the tree, passing each value along with its own measure. Digits
+// and nodes cache their measures but values do not, so each value is measured as it
+// is visited. Returning false stops iteration.
+func (t FingerTree[MS, V, M]) EachMeasured(iter func(v V, m M) bool) {
+	if isEmpty(t.tree()) {
+		return
+	}
+	meas := t.measurer()
+	t.Each(func(v V) bool {
+		return iter(v, meas.Measure(v))
+	})
+}
+
+// Call f on the values of tree, in order, skipping every node, digit, subtree, and
+// value whose measure fails keep. Stop and return false if f returns false.
+func eachPruned(meas measurer, tree fingerTree, keep predicate, f iterFunc) bool {
+	switch tr := force(tree).(type) {
+	case *singleTree:
+		return eachPrunedItem(meas, tr.value, keep, f)
+	case *deepTree:
+		if !keep(tr.measurement().value) {
+			return true
+		}
+		return eachPrunedItems(meas, tr.left, keep, f) &&
+			eachPruned(meas, tr.mid, keep, f) &&
+			eachPrunedItems(meas, tr.right, keep, f)
+	}
+	return true
+}
+
+func eachPrunedItems(meas measurer, d *digit, keep predicate, f iterFunc) bool {
+	if !keep(d._measurement.value) {
+		return true
+	}
+	for _, item := range d.items {
+		if !eachPrunedItem(meas, item, keep, f) {
+			return false
+		}
+	}
+	return true
+}
+
+func eachPrunedItem(meas measurer, item any, keep predicate, f iterFunc) bool {
+	if n, ok := item.(*node); ok {
+		if !keep(n._measurement.value) {
+			return true
+		}
+		for _, child := range n.children {
+			if !eachPrunedItem(meas, child, keep, f) {
+				return false
+			}
+		}
+		return true
+	} else if !keep(meas.Measure(item)) {
+		return true
+	}
+	return f(item)
+}
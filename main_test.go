@@ -1,8 +1,21 @@
 package lazyfingertree
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+	"math/rand"
+	"runtime"
 	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 type width[Value any, M int] int
@@ -88,6 +101,7 @@ func verifyTree(t *testing.T, tree FingerTree[width[int, int], int, int], start
 		return
 	}
 	failIfNot(t, tree.PeekFirst() == start)
+	failIfNot(t, tree.Len() == length)
 	for i := 0; i <= length; i++ {
 		offset := i + start
 		left, right := tree.Split(func(w int) bool {
@@ -112,6 +126,8 @@ func testTree(t *testing.T, size int) {
 		})
 		verifyTree(t, left, 0, i)
 		verifyTree(t, right, i, size-i)
+		l, r := splitAt(tree.f, i)
+		failIfNot(t, same(l.ToSlice(), left.f.ToSlice()) && same(r.ToSlice(), right.f.ToSlice()))
 		merged := left.Concat(right)
 		failIfNot(t, same(tree.ToSlice(), merged.ToSlice()))
 		if i < 2 || i > size-3 {
@@ -139,3 +155,1732 @@ func TestSimple(t *testing.T) {
 		testTree(t, i)
 	}
 }
+
+// measures a sorted tree by its largest value
+type maxValue int
+
+func (m maxValue) Identity() int {
+	return -1
+}
+
+func (m maxValue) Measure(v int) int {
+	return v
+}
+
+func (m maxValue) Sum(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// measures a tree by the sum of its values
+type sumValues int
+
+func (m sumValues) Identity() int {
+	return 0
+}
+
+func (m sumValues) Measure(v int) int {
+	return v
+}
+
+func (m sumValues) Sum(a int, b int) int {
+	return a + b
+}
+
+func TestFindByMeasure(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+	less := func(a, b int) bool { return a < b }
+	tree := FromArray(maxValue(0), []int{1, 3, 5, 7, 9, 11, 13})
+	for i, key := range tree.ToSlice() {
+		v, index, ok := tree.FindByMeasure(key, eq, less)
+		failIfNot(t, ok && v == key && index == i)
+	}
+	for _, key := range []int{0, 4, 12, 14} {
+		_, index, ok := tree.FindByMeasure(key, eq, less)
+		failIfNot(t, !ok && index == -1)
+	}
+	_, _, ok := FromArray(maxValue(0), []int{}).FindByMeasure(1, eq, less)
+	failIfNot(t, !ok)
+	v, index, ok := newTree(10, 20, 30, 40).FindByMeasure(3, eq, less)
+	failIfNot(t, ok && v == 30 && index == 2)
+}
+
+func TestCountInKeyRange(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	keys := []int{1, 3, 3, 5, 7, 9, 9, 9, 11, 13}
+	tree := FromArray(maxValue(0), keys)
+	for _, r := range [][2]int{{0, 100}, {1, 2}, {0, 1}, {3, 9}, {3, 10}, {4, 5}, {13, 14}, {14, 20}, {9, 9}, {9, 3}, {-5, 0}} {
+		expected := 0
+		for _, k := range keys {
+			if k >= r[0] && k < r[1] {
+				expected++
+			}
+		}
+		failIfNot(t, tree.CountInKeyRange(r[0], r[1], less) == expected)
+	}
+	failIfNot(t, FromArray(maxValue(0), []int{}).CountInKeyRange(0, 10, less) == 0)
+}
+
+func TestApplyEdits(t *testing.T) {
+	nums := make([]int, 50)
+	for i := range nums {
+		nums[i] = i
+	}
+	tree := newTree(nums...)
+	edited, err := tree.ApplyEdits([]Edit[int]{
+		{Pos: 0, Delete: 2, Insert: []int{100}},
+		{Pos: 10, Insert: []int{101, 102}},
+		{Pos: 20, Delete: 5},
+		{Pos: 48, Delete: 2, Insert: []int{103}},
+	})
+	failIfErrNow(t, err)
+	expected := []int{100}
+	expected = append(expected, nums[2:10]...)
+	expected = append(expected, 101, 102)
+	expected = append(expected, nums[10:20]...)
+	expected = append(expected, nums[25:48]...)
+	expected = append(expected, 103)
+	failIfNot(t, same(edited.ToSlice(), expected))
+	failIfNot(t, edited.Len() == len(expected) && edited.Measure() == len(expected))
+	failIfNot(t, same(tree.ToSlice(), nums))
+	for _, bad := range [][]Edit[int]{
+		{{Pos: 5, Delete: 3}, {Pos: 7}},
+		{{Pos: 10}, {Pos: 5}},
+		{{Pos: 45, Delete: 6}},
+		{{Pos: 1, Delete: -1}},
+	} {
+		_, err := tree.ApplyEdits(bad)
+		failIfNot(t, errors.Is(err, ErrBadEdit))
+	}
+}
+
+func TestCapFront(t *testing.T) {
+	tree := newTree(1, 2, 3, 4, 5, 6, 7)
+	dropped := []int{}
+	capped := tree.CapFront(3, func(v int) { dropped = append(dropped, v) })
+	failIfNot(t, same(capped.ToSlice(), []int{5, 6, 7}))
+	failIfNot(t, same(dropped, []int{1, 2, 3, 4}))
+	failIfNot(t, capped.Measure() == 3)
+	failIfNot(t, same(tree.CapFront(10, nil).ToSlice(), tree.ToSlice()))
+	failIfNot(t, tree.CapFront(0, nil).IsEmpty())
+}
+
+func TestNearestByMeasure(t *testing.T) {
+	// items start at 0, 10, 30, 35, and 60
+	tree := FromArray(sumValues(0), []int{10, 20, 5, 25, 40})
+	dist := func(a, b int) float64 { return math.Abs(float64(a - b)) }
+	cmp := func(a, b int) int { return a - b }
+	for _, test := range [][3]int{
+		{-5, 0, 10}, {0, 0, 10}, {4, 0, 10}, {6, 1, 20}, {20, 1, 20},
+		{21, 2, 5}, {33, 3, 25}, {50, 4, 40}, {99, 4, 40},
+	} {
+		index, v, ok := tree.NearestByMeasure(test[0], dist, cmp)
+		if !ok || index != test[1] || v != test[2] {
+			t.Errorf("nearest to %d: expected %d, %d but got %d, %d", test[0], test[1], test[2], index, v)
+		}
+	}
+	_, _, ok := FromArray(sumValues(0), []int{}).NearestByMeasure(5, dist, cmp)
+	failIfNot(t, !ok)
+}
+
+func TestStablePartition(t *testing.T) {
+	tree := FromArray(sumValues(0), []int{5, 2, 8, 1, 9, 4, 6})
+	yes, no, yesMeasure, noMeasure := tree.StablePartition(func(v int) bool { return v%2 == 0 })
+	failIfNot(t, same(yes.ToSlice(), []int{2, 8, 4, 6}))
+	failIfNot(t, same(no.ToSlice(), []int{5, 1, 9}))
+	failIfNot(t, yesMeasure == yes.Measure() && yesMeasure == 20)
+	failIfNot(t, noMeasure == no.Measure() && noMeasure == 15)
+}
+
+func TestSplitHalf(t *testing.T) {
+	for size := 0; size < 40; size++ {
+		nums := make([]int, size)
+		for i := range nums {
+			nums[i] = i
+		}
+		tree := FromArray(sumValues(0), nums)
+		left, right := tree.SplitHalf()
+		failIfNot(t, left.Len() == (size+1)/2 && right.Len() == size/2)
+		joined := left.Concat(right)
+		failIfNot(t, same(joined.ToSlice(), nums) && joined.Measure() == tree.Measure())
+	}
+}
+
+func TestEachWithLookahead(t *testing.T) {
+	tree := newTree(0, 1, 2, 3, 4, 5)
+	seen := []int{}
+	tree.EachWithLookahead(2, func(cur int, ahead []int) bool {
+		seen = append(seen, cur)
+		expected := []int{}
+		for i := cur + 1; i <= cur+2 && i < 6; i++ {
+			expected = append(expected, i)
+		}
+		failIfNot(t, same(ahead, expected))
+		return true
+	})
+	failIfNot(t, same(seen, []int{0, 1, 2, 3, 4, 5}))
+	count := 0
+	tree.EachWithLookahead(3, func(cur int, ahead []int) bool {
+		count++
+		return cur < 1
+	})
+	failIfNot(t, count == 2)
+	tree.EachWithLookahead(0, func(cur int, ahead []int) bool {
+		failIfNot(t, len(ahead) == 0)
+		return true
+	})
+}
+
+func TestBuilder(t *testing.T) {
+	for size := 0; size < 200; size += 7 {
+		nums := make([]int, size)
+		for i := range nums {
+			nums[i] = i
+		}
+		b := NewBuilder[width[int, int]](newWidth[int]())
+		b.AddSlice(nums[:size/2])
+		half := b.Tree()
+		for _, n := range nums[size/2:] {
+			b.Add(n)
+		}
+		tree := b.Tree()
+		failIfNot(t, half.Len() == size/2 && b.Len() == size)
+		verifyTree(t, tree, 0, size)
+		failIfNot(t, same(tree.ToSlice(), nums))
+	}
+}
+
+func TestBuilderAddTree(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for round := 0; round < 20; round++ {
+		b := NewBuilder[width[int, int]](newWidth[int]())
+		var expected []int
+		next := func(count int) []int {
+			values := make([]int, count)
+			for i := range values {
+				values[i] = len(expected) + i
+			}
+			expected = append(expected, values...)
+			return values
+		}
+		for step := 0; step < 10; step++ {
+			switch r.Intn(3) {
+			case 0:
+				b.Add(next(1)[0])
+			case 1:
+				b.AddSlice(next(r.Intn(20)))
+			case 2:
+				b.AddTree(newTree(next(r.Intn(100))...))
+			}
+			failIfNot(t, b.Len() == len(expected))
+		}
+		tree := b.Tree()
+		failIfNot(t, same(tree.ToSlice(), expected) && tree.Measure() == len(expected))
+		failIfErrNow(t, MeasuresConsistent(tree))
+	}
+	b := NewBuilder[width[int, int]](newWidth[int]())
+	b.AddTree(FingerTree[width[int, int], int, int]{})
+	b.AddTree(newTree[int]())
+	failIfNot(t, b.Len() == 0 && b.Tree().IsEmpty())
+	strict := newBuilder[width[int, int], int, int](strictMeasurer{adaptedMeasurer[width[int, int], int, int]{newWidth[int]()}})
+	strict.Add(0)
+	strict.AddTree(newTree(1, 2, 3).Concat(newTree(4, 5, 6, 7, 8, 9, 10, 11)))
+	tree := strict.Tree()
+	failIfNot(t, tree.IsStrict() && same(tree.ToSlice(), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}))
+}
+
+func TestFromRecords(t *testing.T) {
+	parse := func(record []string) (int, error) {
+		if len(record) != 2 {
+			return 0, errors.New("expected two fields")
+		}
+		return strconv.Atoi(record[1])
+	}
+	tree, err := FromRecords(sumValues(0), [][]string{{"a", "1"}, {"b", "20"}, {"c", "300"}}, parse)
+	failIfNot(t, err == nil && same(tree.ToSlice(), []int{1, 20, 300}) && tree.Measure() == 321)
+	_, err = FromRecords(sumValues(0), [][]string{{"a", "1"}, {"b", "x"}, {"c"}}, parse)
+	failIfNot(t, errors.Is(err, ErrBadRecord) && errors.Is(err, strconv.ErrSyntax))
+	failIfNot(t, err != nil && strings.Contains(err.Error(), "bad record 1:"))
+}
+
+func TestFromKVSeq(t *testing.T) {
+	combine := func(k string, v int) int { return len(k) * v }
+	tree := FromKVSeq(sumValues(0), slices.Values([]string{"a", "bb", "ccc"}), slices.Values([]int{1, 10, 100}), combine)
+	failIfNot(t, same(tree.ToSlice(), []int{1, 20, 300}) && tree.Measure() == 321)
+	// the shorter sequence ends the tree
+	tree = FromKVSeq(sumValues(0), slices.Values([]string{"a", "bb"}), slices.Values([]int{1, 10, 100}), combine)
+	failIfNot(t, same(tree.ToSlice(), []int{1, 20}))
+	tree = FromKVSeq(sumValues(0), slices.Values([]string{"a", "bb", "ccc"}), slices.Values([]int{5}), combine)
+	failIfNot(t, same(tree.ToSlice(), []int{5}))
+	tree = FromKVSeq(sumValues(0), slices.Values([]string{}), slices.Values([]int{5}), combine)
+	failIfNot(t, tree.IsEmpty() && !tree.IsZero())
+}
+
+func TestEachMeasured(t *testing.T) {
+	nums := []int{4, 0, 9, 2, 7}
+	tree := FromArray(sumValues(0), nums)
+	var visited []int
+	tree.EachMeasured(func(v int, m int) bool {
+		failIfNot(t, m == sumValues(0).Measure(v))
+		visited = append(visited, v)
+		return v != 2
+	})
+	failIfNot(t, same(visited, nums[:4]))
+	count := 0
+	newTree("a", "bb", "ccc").EachMeasured(func(v string, m int) bool {
+		count += m
+		return true
+	})
+	failIfNot(t, count == 3)
+	FromArray(sumValues(0), []int{}).EachMeasured(func(int, int) bool {
+		t.Fail()
+		return true
+	})
+}
+
+func TestEachPruned(t *testing.T) {
+	values := make([]int, 10000)
+	hot := []int{}
+	for i := 37; i < len(values); i += 1500 {
+		values[i] = 100 + i
+		hot = append(hot, values[i])
+	}
+	tree := FromArray(maxValue(0), values)
+	visited := 0
+	found := []int{}
+	tree.EachPruned(func(m int) bool {
+		visited++
+		return m >= 100
+	}, func(v int) bool {
+		found = append(found, v)
+		return true
+	})
+	failIfNot(t, same(found, hot))
+	failIfNot(t, visited*10 < len(values))
+	found = nil
+	tree.EachPruned(func(m int) bool { return m >= 100 }, func(v int) bool {
+		found = append(found, v)
+		return len(found) < 2
+	})
+	failIfNot(t, same(found, hot[:2]))
+	tree.EachPruned(func(m int) bool { return m > 100000 }, func(v int) bool {
+		t.Fatal("visited a rejected value")
+		return true
+	})
+}
+
+func TestFromArrayBoth(t *testing.T) {
+	for size := 0; size < 60; size += 7 {
+		words := make([]string, size)
+		reversed := make([]string, size)
+		for i := range words {
+			words[i] = strconv.Itoa(i)
+			reversed[size-1-i] = words[i]
+		}
+		forward, reverse := FromArrayBoth(newWidth[string](), words)
+		failIfNot(t, same(forward.ToSlice(), words) && same(reverse.ToSlice(), reversed))
+		failIfNot(t, forward.Len() == size && reverse.Len() == size)
+		failIfNot(t, MeasuresConsistent(forward) == nil && MeasuresConsistent(reverse) == nil)
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	a := newTree(1, 3, 5, 7, 9, 11)
+	b := newTree(2, 4, 6)
+	failIfNot(t, same(Interleave(a, b).ToSlice(), []int{1, 2, 3, 4, 5, 6, 7, 9, 11}))
+	failIfNot(t, same(Interleave(b, a).ToSlice(), []int{2, 1, 4, 3, 6, 5, 7, 9, 11}))
+	failIfNot(t, Interleave(a, b).Measure() == 9)
+	failIfNot(t, same(Interleave(newTree[int](), b).ToSlice(), b.ToSlice()))
+}
+
+func TestGet(t *testing.T) {
+	nums := make([]int, 100)
+	for i := range nums {
+		nums[i] = i * 2
+	}
+	tree := newTree(nums...)
+	for i, n := range nums {
+		failIfNot(t, tree.Get(i) == n)
+	}
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrOutOfRange))
+	}()
+	tree.Get(100)
+}
+
+func TestNeighborhood(t *testing.T) {
+	tree := newTree(10, 20, 30, 40, 50, 60, 70, 80, 90, 100)
+	prev, cur, next, hasPrev, hasNext := tree.Neighborhood(0)
+	failIfNot(t, !hasPrev && prev == 0 && cur == 10 && hasNext && next == 20)
+	prev, cur, next, hasPrev, hasNext = tree.Neighborhood(5)
+	failIfNot(t, hasPrev && prev == 50 && cur == 60 && hasNext && next == 70)
+	prev, cur, next, hasPrev, hasNext = tree.Neighborhood(9)
+	failIfNot(t, hasPrev && prev == 90 && cur == 100 && !hasNext && next == 0)
+	_, cur, _, hasPrev, hasNext = newTree(7).Neighborhood(0)
+	failIfNot(t, cur == 7 && !hasPrev && !hasNext)
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrOutOfRange))
+	}()
+	tree.Neighborhood(-1)
+}
+
+// Return a tree of 1 through 198 whose mid tree is a suspension with an unknown
+// measure, between left and right digits of three values, and the flag that makes
+// forcing it panic.
+func suspendedMid() (FingerTree[slowWidth, int, int], *bool) {
+	broken := false
+	nums := make([]int, 200)
+	for i := range nums {
+		nums[i] = i
+	}
+	tree := FromArray(slowWidth{panic: &broken}, nums).RemoveFirst().RemoveLast()
+	broken = true
+	return tree, &broken
+}
+
+func TestZipWith(t *testing.T) {
+	widths := newTree(1, 2, 3, 4)
+	names := newTree("a", "b", "c")
+	cells := ZipWith(widths, names, newWidth[string](), func(w int, n string) string {
+		return strings.Repeat(n, w)
+	})
+	failIfNot(t, same(cells.ToSlice(), []string{"a", "bb", "ccc"}) && cells.Measure() == 3)
+	sums := ZipWith(names, widths, sumValues(0), func(n string, w int) int { return w * 10 })
+	failIfNot(t, same(sums.ToSlice(), []int{10, 20, 30}) && sums.Measure() == 60)
+	failIfNot(t, ZipWith(newTree[int](), names, newWidth[string](), func(int, string) string { return "" }).IsEmpty())
+	failIfNot(t, ZipWith(widths, newTree[string](), newWidth[string](), func(int, string) string { return "" }).IsEmpty())
+	// the values after the joined tree's left digit are in its suspended mid tree, and
+	// forcing that panics
+	broken := false
+	meas := slowWidth{panic: &broken}
+	long := FromArray(meas, []int{0, 1, 2, 3, 4, 5, 6, 7}).Concat(FromArray(meas, []int{8, 9, 10, 11, 12, 13, 14, 15}))
+	prefix := long.LazyProfile()[0].Left
+	failIfNot(t, long.LazyProfile()[1].Kind == "pending")
+	broken = true
+	short := newTree([]string{"a", "b", "c", "d", "e", "f"}[:prefix]...)
+	pairs := ZipWith(long, short, newWidth[string](), func(v int, n string) string {
+		return n + strconv.Itoa(v)
+	})
+	failIfNot(t, pairs.Len() == prefix && pairs.PeekFirst() == "a0")
+	// the length of a tree with a suspended RemoveLast is not known without forcing it
+	suspended, _ := suspendedMid()
+	failIfNot(t, suspended.LazyProfile()[1].Kind == "pending")
+	pairs = ZipWith(newTree("a", "b"), suspended, newWidth[string](), func(n string, v int) string {
+		return n + strconv.Itoa(v)
+	})
+	failIfNot(t, same(pairs.ToSlice(), []string{"a1", "b2"}))
+	pairs = ZipWith(suspended, newTree("a", "b", "c"), newWidth[string](), func(v int, n string) string {
+		return n + strconv.Itoa(v)
+	})
+	failIfNot(t, same(pairs.ToSlice(), []string{"a1", "b2", "c3"}))
+}
+
+func TestRunningMax(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	nums := make([]int, 200)
+	for i := range nums {
+		nums[i] = r.Intn(1000)
+	}
+	maxes := RunningMax(newTree(nums...), maxValue(0), func(a, b int) bool { return a > b }).ToSlice()
+	failIfNot(t, len(maxes) == len(nums))
+	for i := range nums {
+		failIfNot(t, maxes[i] >= nums[i] && (i == 0 || maxes[i] >= maxes[i-1]))
+		failIfNot(t, maxes[i] == nums[i] || (i > 0 && maxes[i] == maxes[i-1]))
+	}
+	failIfNot(t, RunningMax(newTree[int](), maxValue(0), func(a, b int) bool { return a > b }).IsEmpty())
+}
+
+func TestScan(t *testing.T) {
+	totals := Scan(newTree(1, 2, 3, 4), 10, func(acc int, v int) int { return acc + v }, sumValues(0))
+	failIfNot(t, same(totals.ToSlice(), []int{11, 13, 16, 20}))
+	failIfNot(t, totals.Measure() == 60)
+	lengths := Scan(newTree("a", "bb", "ccc"), "", func(acc string, v string) string { return acc + v }, newWidth[string]())
+	failIfNot(t, same(lengths.ToSlice(), []string{"a", "abb", "abbccc"}))
+	failIfNot(t, Scan(newTree[int](), 0, func(acc int, v int) int { return acc + v }, sumValues(0)).IsEmpty())
+}
+
+func TestCumulativeMeasureTree(t *testing.T) {
+	nums := []int{5, 1, 4, 0, 10}
+	prefixes := CumulativeMeasureTree(FromArray(sumValues(0), nums), maxValue(0))
+	failIfNot(t, same(prefixes.ToSlice(), []int{5, 6, 10, 10, 20}) && prefixes.Measure() == 20)
+	counts := CumulativeMeasureTree(newTree("a", "b", "c"), sumValues(0))
+	failIfNot(t, same(counts.ToSlice(), []int{1, 2, 3}) && counts.Measure() == 6)
+	failIfNot(t, CumulativeMeasureTree(newTree[int](), sumValues(0)).IsEmpty())
+	var zero FingerTree[sumValues, int, int]
+	failIfNot(t, CumulativeMeasureTree(zero, sumValues(0)).IsEmpty())
+}
+
+func TestReduceWhile(t *testing.T) {
+	tree := newTree(3, 4, 5, 6, 7, 8, 9)
+	visited := 0
+	sum, complete := ReduceWhile(tree, 0, func(acc int, v int) (int, bool) {
+		visited++
+		if acc+v > 12 {
+			return acc, false
+		}
+		return acc + v, true
+	})
+	failIfNot(t, sum == 12 && !complete && visited == 4)
+	sum, complete = ReduceWhile(tree, 0, func(acc int, v int) (int, bool) { return acc + v, true })
+	failIfNot(t, sum == 42 && complete)
+	sum, complete = ReduceWhile(newTree[int](), 7, func(acc int, v int) (int, bool) { return acc + v, true })
+	failIfNot(t, sum == 7 && complete)
+}
+
+func TestIterator(t *testing.T) {
+	nums := make([]int, 100)
+	for i := range nums {
+		nums[i] = i * 3
+	}
+	next := newTree(nums...).Iterator()
+	pulled := []int{}
+	for v, ok := next(); ok; v, ok = next() {
+		pulled = append(pulled, v)
+	}
+	failIfNot(t, same(pulled, nums))
+	v, ok := next()
+	failIfNot(t, !ok && v == 0)
+	_, ok = newTree[int]().Iterator()()
+	failIfNot(t, !ok)
+}
+
+func TestHashWith(t *testing.T) {
+	writeInt := func(h hash.Hash, v int) {
+		binary.Write(h, binary.LittleEndian, int64(v))
+	}
+	hashOf := func(tree FingerTree[width[int, int], int, int]) []byte {
+		return tree.HashWith(sha256.New(), writeInt)
+	}
+	a := hashOf(newTree(1, 2, 3, 4, 5))
+	failIfNot(t, bytes.Equal(a, hashOf(newTree(1, 2).Concat(newTree(3, 4, 5)))))
+	failIfNot(t, !bytes.Equal(a, hashOf(newTree(2, 1, 3, 4, 5))))
+	failIfNot(t, !bytes.Equal(a, hashOf(newTree(1, 2, 3, 4))))
+	failIfNot(t, bytes.Equal(hashOf(newTree[int]()), sha256.New().Sum(nil)))
+}
+
+func TestEachBatch(t *testing.T) {
+	nums := make([]int, 23)
+	for i := range nums {
+		nums[i] = i
+	}
+	tree := newTree(nums...)
+	sizes := []int{}
+	all := []int{}
+	tree.EachBatch(5, func(batch []int) bool {
+		sizes = append(sizes, len(batch))
+		all = append(all, batch...)
+		return true
+	})
+	failIfNot(t, same(sizes, []int{5, 5, 5, 5, 3}) && same(all, nums))
+	calls := 0
+	tree.EachBatch(5, func(batch []int) bool {
+		calls++
+		return calls < 2
+	})
+	failIfNot(t, calls == 2)
+	tree.EachBatch(23, func(batch []int) bool {
+		calls++
+		return false
+	})
+	failIfNot(t, calls == 3)
+}
+
+func TestEachChunked(t *testing.T) {
+	for _, size := range []int{0, 1, 7, 100, 1000} {
+		nums := make([]int, size)
+		for i := range nums {
+			nums[i] = i
+		}
+		tree := newTree(nums...)
+		for _, maxBatch := range []int{0, 1, 3, 64, 2000} {
+			all := []int{}
+			tree.EachChunked(maxBatch, func(batch []int) bool {
+				failIfNot(t, len(batch) > 0 && len(batch) <= max(maxBatch, 1))
+				failIfNot(t, len(batch) == max(maxBatch, 1) || len(all)+len(batch) == size)
+				all = append(all, batch...)
+				return true
+			})
+			failIfNot(t, same(all, nums))
+		}
+		if size > 0 {
+			calls := 0
+			tree.EachChunked(3, func(batch []int) bool {
+				calls++
+				failIfNot(t, batch[0] == 0)
+				return false
+			})
+			failIfNot(t, calls == 1)
+		}
+	}
+}
+
+func BenchmarkEachSum(b *testing.B) {
+	tree := FromArray(sumValues(0), make([]int, 100000))
+	for i := 0; i < b.N; i++ {
+		total := 0
+		tree.Each(func(v int) bool {
+			total += v
+			return true
+		})
+	}
+}
+
+func BenchmarkEachChunkedSum(b *testing.B) {
+	tree := FromArray(sumValues(0), make([]int, 100000))
+	for i := 0; i < b.N; i++ {
+		total := 0
+		tree.EachChunked(256, func(batch []int) bool {
+			for _, v := range batch {
+				total += v
+			}
+			return true
+		})
+	}
+}
+
+func TestMapAccum(t *testing.T) {
+	type cell struct {
+		text string
+		pos  int
+	}
+	end, cells := MapAccum(newTree("ab", "cde", "f"), 0, func(pos int, s string) (int, cell) {
+		return pos + len(s), cell{s, pos}
+	}, newWidth[cell]())
+	failIfNot(t, end == 6)
+	failIfNot(t, same(cells.ToSlice(), []cell{{"ab", 0}, {"cde", 2}, {"f", 5}}))
+	failIfNot(t, cells.Measure() == 3)
+}
+
+func TestSplitStrict(t *testing.T) {
+	tree := newTree(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	left, right, err := tree.SplitStrict(func(w int) bool { return w > 4 })
+	failIfErrNow(t, err)
+	failIfNot(t, left.Len() == 4 && right.Len() == 6)
+	left, right, err = tree.SplitStrict(func(w int) bool { return w >= 0 })
+	failIfNot(t, err == nil && left.IsEmpty() && right.Len() == 10)
+	// a predicate that is only true the first time it sees the whole tree
+	fickle := func() Predicate[int] {
+		first := true
+		return func(w int) bool {
+			result := first && w == 10
+			first = first && w != 10
+			return result
+		}
+	}
+	_, _, err = tree.SplitStrict(fickle())
+	failIfNot(t, errors.Is(err, ErrBadPredicate))
+	CheckSplits = true
+	defer func() {
+		CheckSplits = false
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrBadPredicate))
+	}()
+	tree.Split(fickle())
+	t.Fail()
+}
+
+// Splits with predicates that are true at the identity measure split off nothing
+func TestSplitIdentity(t *testing.T) {
+	preds := []struct {
+		name string
+		pred Predicate[int]
+		// whether everything goes to the right tree
+		allRight bool
+	}{
+		{"always", func(int) bool { return true }, true},
+		{"never", func(int) bool { return false }, false},
+		{"at identity", func(w int) bool { return w >= 0 }, true},
+	}
+	for _, size := range []int{0, 1, 2, 5, 30, 200} {
+		values := make([]int, size)
+		for i := range values {
+			values[i] = i
+		}
+		lazy := newTree(values...)
+		for i := 0; i < size/4; i++ {
+			lazy = lazy.RemoveFirst().AddFirst(values[0])
+		}
+		for _, tree := range []FingerTree[width[int, int], int, int]{newTree(values...), lazy} {
+			for _, test := range preds {
+				everything, nothing := tree, newTree[int]()
+				if !test.allRight {
+					everything, nothing = nothing, everything
+				}
+				left, right := tree.Split(test.pred)
+				failIfNot(t, same(left.ToSlice(), nothing.ToSlice()) && same(right.ToSlice(), everything.ToSlice()))
+				failIfNot(t, left.Measure() == nothing.Len() && right.Measure() == everything.Len())
+				failIfNot(t, same(tree.TakeUntil(test.pred).ToSlice(), nothing.ToSlice()))
+				failIfNot(t, same(tree.DropUntil(test.pred).ToSlice(), everything.ToSlice()))
+				left, right, err := tree.SplitStrict(test.pred)
+				failIfNot(t, err == nil && same(left.ToSlice(), nothing.ToSlice()) && same(right.ToSlice(), everything.ToSlice()))
+				chunkLeft, chunkRight := FromArrayChunked(newWidth[int](), values, 4).Split(test.pred)
+				failIfNot(t, same(chunkLeft.ToSlice(), nothing.ToSlice()) && same(chunkRight.ToSlice(), everything.ToSlice()))
+				if !t.Failed() {
+					continue
+				}
+				t.Fatalf("%s split of %d values", test.name, size)
+			}
+		}
+	}
+}
+
+func TestAsIndexed(t *testing.T) {
+	nums := make([]int, 300)
+	for i := range nums {
+		nums[i] = i * 3
+	}
+	view := newTree(nums...).AsIndexed()
+	failIfNot(t, view.Len() == len(nums))
+	for i, n := range nums {
+		failIfNot(t, view.At(i) == n)
+	}
+	for i := len(nums) - 1; i >= 0; i -= 7 {
+		failIfNot(t, view.At(i) == nums[i])
+	}
+}
+
+func hasSuspension(tree fingerTree) bool {
+	switch t := tree.(type) {
+	case *delayed:
+		return true
+	case *deepTree:
+		return hasSuspension(t.mid)
+	}
+	return false
+}
+
+func TestStrict(t *testing.T) {
+	nums := make([]int, 500)
+	for i := range nums {
+		nums[i] = i
+	}
+	tree := FromArrayStrict[width[int, int]](newWidth[int](), nums)
+	failIfNot(t, tree.IsStrict() && !newTree(nums...).IsStrict())
+	failIfNot(t, newTree(nums...).Strict().IsStrict())
+	for i := 0; i <= len(nums); i += 13 {
+		left, right := tree.Split(func(w int) bool { return w > i })
+		failIfNot(t, !hasSuspension(left.f) && !hasSuspension(right.f))
+		failIfNot(t, left.IsStrict() && right.IsStrict())
+		verifyTree(t, left, 0, i)
+		verifyTree(t, right, i, len(nums)-i)
+		joined := right.Concat(left)
+		failIfNot(t, !hasSuspension(joined.f))
+		failIfNot(t, same(joined.ToSlice(), append(Dup(nums[i:]), nums[:i]...)))
+	}
+	for shrinking := tree; !shrinking.IsEmpty(); shrinking = shrinking.RemoveFirst().RemoveLast() {
+		failIfNot(t, !hasSuspension(shrinking.f))
+	}
+}
+
+// Concats build up a chain of suspensions in lazy trees which the final
+// RemoveLast forces all at once
+func benchmarkWorstCase(b *testing.B, strict bool) {
+	worst := time.Duration(0)
+	for n := 0; n < b.N; n++ {
+		tree := newTree(0, 0, 0)
+		if strict {
+			tree = tree.Strict()
+		}
+		timed := func(op func()) {
+			start := time.Now()
+			op()
+			if elapsed := time.Since(start); elapsed > worst {
+				worst = elapsed
+			}
+		}
+		for i := 0; i < 5000; i++ {
+			timed(func() { tree = tree.Concat(newTree(i, i, i)) })
+		}
+		timed(func() { tree = tree.RemoveLast().RemoveLast().RemoveLast() })
+	}
+	b.ReportMetric(float64(worst.Nanoseconds()), "worst-ns")
+}
+
+func BenchmarkLazyWorstCase(b *testing.B) {
+	benchmarkWorstCase(b, false)
+}
+
+func BenchmarkStrictWorstCase(b *testing.B) {
+	benchmarkWorstCase(b, true)
+}
+
+// Return strings with heavy repetition where every string has its own allocation.
+func repeatedStrings(n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = strings.Repeat(string(rune('a'+i%7)), 40)
+	}
+	return words
+}
+
+func identity[V any](v V) V {
+	return v
+}
+
+func TestInterning(t *testing.T) {
+	words := repeatedStrings(100)
+	plain := FromArray(newWidth[string](), words)
+	interned := FromArray(newWidth[string](), words, WithInterning(identity[string], 0))
+	failIfNot(t, same(interned.ToSlice(), words) && interned.Measure() == plain.Measure())
+	first := map[string]*byte{}
+	interned.Each(func(w string) bool {
+		if first[w] == nil {
+			first[w] = unsafe.StringData(w)
+		}
+		failIfNot(t, unsafe.StringData(w) == first[w])
+		return true
+	})
+	b := NewBuilder(newWidth[string](), WithInterning(identity[string], 3))
+	b.AddSlice(words)
+	built := b.Tree().ToSlice()
+	failIfNot(t, same(built, words))
+	failIfNot(t, unsafe.StringData(built[7]) == unsafe.StringData(built[0]))
+	failIfNot(t, unsafe.StringData(built[8]) == unsafe.StringData(built[1]))
+	failIfNot(t, unsafe.StringData(built[11]) == unsafe.StringData(words[11]))
+}
+
+func benchmarkInterning(b *testing.B, opts ...BuildOption[string]) {
+	var retained uint64
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		tree := FromArray(newWidth[string](), repeatedStrings(100000), opts...)
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		retained += after.HeapAlloc - before.HeapAlloc
+		runtime.KeepAlive(tree)
+	}
+	b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+}
+
+func BenchmarkPlainStrings(b *testing.B) {
+	benchmarkInterning(b)
+}
+
+func BenchmarkInternedStrings(b *testing.B) {
+	benchmarkInterning(b, WithInterning(identity[string], 0))
+}
+
+func TestQuantiles(t *testing.T) {
+	tree := FromArray(sumValues(0), []int{1, 2, 3, 4})
+	weight := func(m int) float64 { return float64(m) }
+	failIfNot(t, same(tree.Quantiles([]float64{0.25, 0.5, 0.75}, weight), []int{2, 3, 4}))
+	failIfNot(t, same(tree.Quantiles([]float64{0, 0.1, 0.1, 1, 2}, weight), []int{1, 1, 1, 4, 4}))
+	nums := make([]int, 100)
+	for i := range nums {
+		nums[i] = i
+	}
+	counted := newTree(nums...).Quantiles([]float64{0.01, 0.5, 0.505, 1}, weight)
+	failIfNot(t, same(counted, []int{0, 49, 50, 99}))
+	failIfNot(t, FromArray(sumValues(0), []int{}).Quantiles([]float64{0.5}, weight) == nil)
+}
+
+type anyWidth interface {
+	Measurer[int, int]
+}
+
+func TestRewrap(t *testing.T) {
+	tree := newTree(1, 2, 3)
+	general := Rewrap[anyWidth](tree)
+	failIfNot(t, same(general.AddLast(4).ToSlice(), []int{1, 2, 3, 4}) && general.Measure() == 3)
+	checked, err := RewrapChecked[anyWidth](tree)
+	failIfErrNow(t, err)
+	failIfNot(t, checked.Len() == 3)
+	_, err = RewrapChecked[maxValue](tree)
+	failIfNot(t, errors.Is(err, ErrBadMeasurer))
+	_, err = RewrapChecked[anyWidth](tree.Strict())
+	failIfErrNow(t, err)
+}
+
+func TestFoldStates(t *testing.T) {
+	tree := newTree(1, 2, 3, 4, 5)
+	states := []int{}
+	for acc := range FoldStates(tree, 0, func(acc int, v int) int { return acc + v }) {
+		states = append(states, acc)
+	}
+	failIfNot(t, same(states, []int{1, 3, 6, 10, 15}))
+	states = states[:0]
+	visited := 0
+	for acc := range FoldStates(tree, 100, func(acc int, v int) int {
+		visited++
+		return acc - v
+	}) {
+		states = append(states, acc)
+		if len(states) == 2 {
+			break
+		}
+	}
+	failIfNot(t, same(states, []int{99, 97}) && visited == 2)
+}
+
+func TestCrossProduct(t *testing.T) {
+	type pair struct{ a, b int }
+	var pairs []pair
+	for a, b := range CrossProduct(newTree(1, 2, 3), newTree(10, 20)) {
+		pairs = append(pairs, pair{a, b})
+	}
+	failIfNot(t, same(pairs, []pair{{1, 10}, {1, 20}, {2, 10}, {2, 20}, {3, 10}, {3, 20}}))
+	count := 0
+	for range CrossProduct(newTree(1, 2, 3), newTree[int]()) {
+		count++
+	}
+	failIfNot(t, count == 0)
+	big := make([]int, 100)
+	for i := range big {
+		big[i] = i
+	}
+	for range CrossProduct(newTree(big...), newTree(big...)) {
+		count++
+	}
+	failIfNot(t, count == 10000)
+	count = 0
+	for a, b := range CrossProduct(newTree(big...), newTree(big...)) {
+		count++
+		if a == 2 && b == 5 {
+			break
+		}
+	}
+	failIfNot(t, count == 206)
+}
+
+func TestToSliceReverse(t *testing.T) {
+	for size := 0; size < 50; size += 7 {
+		nums := make([]int, size)
+		for i := range nums {
+			nums[i] = i
+		}
+		reversed := newTree(nums...).ToSliceReverse()
+		failIfNot(t, len(reversed) == size)
+		for i := range nums {
+			failIfNot(t, reversed[i] == nums[size-1-i])
+		}
+	}
+}
+
+func TestAddLastDepth(t *testing.T) {
+	tree := newTree[int]()
+	growths := []int{}
+	for i := 0; i < 200; i++ {
+		var deeper bool
+		before := spineDepth(tree.f)
+		tree, deeper = tree.AddLastDepth(i)
+		failIfNot(t, deeper == (spineDepth(tree.f) > before))
+		if deeper {
+			growths = append(growths, i)
+		}
+	}
+	failIfNot(t, same(tree.ToSlice()[:5], []int{0, 1, 2, 3, 4}))
+	// the first value, then the first node at each new level of the spine
+	failIfNot(t, same(growths, []int{0, 5, 20, 65}))
+}
+
+func TestRunLength(t *testing.T) {
+	for _, values := range [][]int{
+		{},
+		{1, 2, 3, 4},
+		{1, 1, 1, 2, 2, 3, 1, 1},
+		{7, 7, 7, 7, 7, 7, 7, 7, 7, 7, 7},
+	} {
+		runs := RunLengthEncode(newTree(values...))
+		total := 0
+		for i, run := range runs {
+			failIfNot(t, run.Count > 0 && (i == 0 || runs[i-1].Value != run.Value))
+			total += run.Count
+		}
+		failIfNot(t, total == len(values))
+		decoded := RunLengthDecode[width[int, int]](newWidth[int](), runs)
+		failIfNot(t, same(decoded.ToSlice(), values))
+	}
+	failIfNot(t, same(RunLengthEncode(newTree(1, 1, 2, 1)), []Run[int]{{1, 2}, {2, 1}, {1, 1}}))
+}
+
+func TestMeasureExcluding(t *testing.T) {
+	tree := FromArray(SumMeasurer[int]{}, []int{5, 10, 20, 40})
+	m, err := tree.MeasureExcluding([]int{10, 40})
+	failIfErrNow(t, err)
+	failIfNot(t, m == 25)
+	m, err = tree.MeasureExcluding(nil)
+	failIfNot(t, err == nil && m == 75)
+	_, err = FromArray(sumValues(0), []int{1}).MeasureExcluding([]int{1})
+	failIfNot(t, errors.Is(err, ErrUnsupported))
+}
+
+type sizes struct {
+	Bytes int64
+	Lines uint16
+}
+
+type chunkInfo struct {
+	sizes
+	Name  string
+	Words int32
+}
+
+type linkedChunk struct {
+	*sizes
+	Words int
+}
+
+func TestNoMeasurer(t *testing.T) {
+	values := make([]int, 500)
+	for i := range values {
+		values[i] = i
+	}
+	deque := FromArray(NoMeasurer[int]{}, values)
+	deque = deque.AddFirst(-1).AddLast(500).RemoveFirst()
+	failIfNot(t, deque.Len() == 501 && deque.PeekFirst() == 0 && deque.PeekLast() == 500)
+	failIfNot(t, deque.Get(250) == 250 && deque.Measure() == struct{}{})
+	left, right := deque.SplitHalf()
+	failIfNot(t, same(left.Concat(right).ToSlice(), append(Dup(values), 500)))
+	failIfNot(t, same(deque.DeleteRange(1, 501).ToSlice(), []int{0}))
+	b := NewBuilder(NoMeasurer[int]{})
+	b.AddSlice(values)
+	failIfNot(t, same(b.Tree().ToSlice(), values))
+	_, ok := any(deque.measurer()).(NoMeasurer[int])
+	failIfNot(t, ok && FromArrayStrict(NoMeasurer[int]{}, values).IsStrict())
+}
+
+func benchmarkDeque[MS Measurer[int, M], M any](b *testing.B, measurer MS) {
+	values := make([]int, 1000)
+	tree := FromArray(measurer, values)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree = tree.AddLast(i).RemoveFirst()
+		if i%64 == 0 {
+			tree = tree.AddFirst(i).RemoveLast()
+		}
+	}
+}
+
+func BenchmarkDequeCounted(b *testing.B) {
+	benchmarkDeque(b, newWidth[int]())
+}
+
+func BenchmarkDequeUnmeasured(b *testing.B) {
+	benchmarkDeque(b, NoMeasurer[int]{})
+}
+
+func TestPointUpdate(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	counts := make([]int, 300)
+	for i := range counts {
+		counts[i] = r.Intn(10)
+	}
+	tree := FromArray(SumMeasurer[int]{}, counts)
+	counts = Dup(counts)
+	old := tree
+	oldTotal := tree.Measure()
+	for step := 0; step < 500; step++ {
+		index := r.Intn(len(counts))
+		delta := r.Intn(5)
+		tree = PointUpdate(tree, index, delta)
+		counts[index] += delta
+		k := r.Intn(len(counts) + 1)
+		prefix, _ := splitAt(tree.f, k)
+		expected := 0
+		for _, c := range counts[:k] {
+			expected += c
+		}
+		failIfNot(t, wrapTree[SumMeasurer[int], int, int](prefix).Measure() == expected)
+		// the lower bound is the first index where the prefix sum passes target
+		target := r.Intn(tree.Measure() + 1)
+		left, _ := tree.Split(func(m int) bool { return m > target })
+		bound, sum := 0, 0
+		for ; bound < len(counts) && sum+counts[bound] <= target; bound++ {
+			sum += counts[bound]
+		}
+		failIfNot(t, left.Len() == bound)
+	}
+	failIfNot(t, same(tree.ToSlice(), counts) && old.Measure() == oldTotal)
+	failIfErrNow(t, MeasuresConsistent(tree))
+	failIfNot(t, same(PointUpdate(FromArray(SumMeasurer[float64]{}, []float64{1, 2}), 1, -0.5).ToSlice(), []float64{1, 1.5}))
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrOutOfRange))
+	}()
+	PointUpdate(tree, len(counts), 1)
+	t.Fail()
+}
+
+func TestStructMeasurer(t *testing.T) {
+	meas, err := StructMeasurer[chunkInfo]("Bytes", "Lines", "Words")
+	failIfErrNow(t, err)
+	tree := FromArray(meas, []chunkInfo{
+		{sizes{10, 1}, "a", 2},
+		{sizes{20, 3}, "b", 4},
+		{sizes{5, 0}, "c", 1},
+	})
+	m := tree.Measure()
+	failIfNot(t, len(m) == 3 && m["Bytes"] == 35 && m["Lines"] == 4 && m["Words"] == 7)
+	left, right := tree.Split(func(m map[string]int64) bool { return m["Bytes"] > 10 })
+	failIfNot(t, left.Len() == 1 && right.PeekFirst().Name == "b")
+	failIfNot(t, len(FromArray(meas, []chunkInfo{}).Measure()) == 3)
+	ptrMeas, err := StructMeasurer[*chunkInfo]("Lines")
+	failIfErrNow(t, err)
+	ptrTree := FromArray(ptrMeas, []*chunkInfo{{sizes: sizes{Lines: 2}}, nil, {sizes: sizes{Lines: 5}}})
+	failIfNot(t, ptrTree.Measure()["Lines"] == 7)
+	linkedMeas, err := StructMeasurer[linkedChunk]("Bytes", "Words")
+	failIfErrNow(t, err)
+	linked := FromArray(linkedMeas, []linkedChunk{{&sizes{Bytes: 8}, 1}, {nil, 2}})
+	failIfNot(t, linked.Measure()["Bytes"] == 8 && linked.Measure()["Words"] == 3)
+	for _, bad := range [][]string{{"Missing"}, {"Name"}, {"Bytes", "Bytes"}, {}} {
+		_, err = StructMeasurer[chunkInfo](bad...)
+		failIfNot(t, errors.Is(err, ErrBadMeasurer))
+	}
+	_, err = StructMeasurer[int]("Bytes")
+	failIfNot(t, errors.Is(err, ErrBadMeasurer))
+}
+
+func TestTracingMeasurer(t *testing.T) {
+	var events []TraceEvent
+	meas := TracingMeasurer[int, int](SumMeasurer[int]{}, RecordTrace(&events))
+	tree := FromArray(meas, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	failIfNot(t, tree.Measure() == 55 && len(events) > 0)
+	for i, event := range events {
+		failIfNot(t, event.Seq == uint64(i+1))
+		switch event.Op {
+		case "Identity":
+			failIfNot(t, len(event.Args) == 0 && event.Result == 0)
+		case "Measure":
+			failIfNot(t, len(event.Args) == 1 && event.Result == event.Args[0])
+		case "Sum":
+			failIfNot(t, len(event.Args) == 2 && event.Result == event.Args[0].(int)+event.Args[1].(int))
+		default:
+			t.Fatalf("unexpected op %q", event.Op)
+		}
+	}
+	events = events[:0]
+	left, right := tree.Split(func(m int) bool { return m > 20 })
+	failIfNot(t, left.Len() == 5 && right.PeekFirst() == 6)
+	// the predicate is true first for the sum ending with 6
+	var sawBoundary bool
+	for _, event := range events {
+		sawBoundary = sawBoundary || event.Op == "Sum" && event.Result == 21
+	}
+	failIfNot(t, sawBoundary)
+	var buf strings.Builder
+	small := FromArray(TracingMeasurer[int, int](SumMeasurer[int]{}, WriteTrace(&buf)), []int{4})
+	failIfNot(t, small.Measure() == 4)
+	failIfNot(t, strings.Contains(buf.String(), "Measure(4) = 4\n"))
+	failIfNot(t, strings.HasPrefix(buf.String(), "#1 "))
+	failIfNot(t, TraceEvent{3, "Sum", []any{1, 2}, 3}.String() == "#3 Sum(1, 2) = 3")
+}
+
+func TestContainsSubsequence(t *testing.T) {
+	tree := newTree(1, 2, 1, 2, 1, 3, 4, 5, 1, 2, 1, 2, 9)
+	eq := func(a, b int) bool { return a == b }
+	failIfNot(t, tree.ContainsSubsequence([]int{1, 2, 1}, eq))
+	failIfNot(t, tree.ContainsSubsequence([]int{1, 2, 1, 3}, eq))
+	failIfNot(t, tree.ContainsSubsequence([]int{3, 4, 5}, eq))
+	failIfNot(t, tree.ContainsSubsequence([]int{1, 2, 9}, eq))
+	failIfNot(t, tree.ContainsSubsequence(tree.ToSlice(), eq))
+	failIfNot(t, !tree.ContainsSubsequence([]int{1, 2, 1, 2, 1, 2}, eq))
+	failIfNot(t, !tree.ContainsSubsequence([]int{3, 5}, eq))
+	failIfNot(t, !tree.ContainsSubsequence(append(tree.ToSlice(), 0), eq))
+	failIfNot(t, tree.ContainsSubsequence(nil, eq) && newTree[int]().ContainsSubsequence(nil, eq))
+	failIfNot(t, !newTree[int]().ContainsSubsequence([]int{1}, eq))
+}
+
+func segments(trees []FingerTree[width[int, int], int, int]) [][]int {
+	result := make([][]int, len(trees))
+	for i, tree := range trees {
+		result[i] = tree.ToSlice()
+	}
+	return result
+}
+
+func TestSplitOn(t *testing.T) {
+	isZero := func(v int) bool { return v == 0 }
+	split := segments(newTree(0, 1, 2, 0, 0, 3, 0).SplitOn(isZero))
+	failIfNot(t, len(split) == 5)
+	failIfNot(t, len(split[0]) == 0 && same(split[1], []int{1, 2}) && len(split[2]) == 0)
+	failIfNot(t, same(split[3], []int{3}) && len(split[4]) == 0)
+	fields := segments(newTree(0, 1, 2, 0, 0, 3, 0).Fields(isZero))
+	failIfNot(t, len(fields) == 2 && same(fields[0], []int{1, 2}) && same(fields[1], []int{3}))
+	whole := segments(newTree(1, 2, 3).SplitOn(isZero))
+	failIfNot(t, len(whole) == 1 && same(whole[0], []int{1, 2, 3}))
+	failIfNot(t, len(newTree[int]().SplitOn(isZero)) == 1 && len(newTree[int]().Fields(isZero)) == 0)
+	failIfNot(t, len(newTree(0, 0).Fields(isZero)) == 0 && len(newTree(0, 0).SplitOn(isZero)) == 3)
+}
+
+func TestEveryNth(t *testing.T) {
+	tree := newTree(0, 1, 2, 3, 4, 5, 6)
+	failIfNot(t, same(tree.EveryNth(1).ToSlice(), tree.ToSlice()))
+	failIfNot(t, same(tree.EveryNth(2).ToSlice(), []int{0, 2, 4, 6}) && tree.EveryNth(2).Measure() == 4)
+	failIfNot(t, same(tree.EveryNth(3).ToSlice(), []int{0, 3, 6}))
+	failIfNot(t, same(tree.EveryNth(100).ToSlice(), []int{0}))
+	failIfNot(t, newTree[int]().EveryNth(2).IsEmpty())
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrOutOfRange))
+	}()
+	tree.EveryNth(0)
+}
+
+func TestMatchRange(t *testing.T) {
+	tree := newTree(1, 4, 3, 8, 5, 6, 7, 9)
+	isEven := func(v int) bool { return v%2 == 0 }
+	first, last, ok := tree.MatchRange(isEven)
+	failIfNot(t, ok && first == 1 && last == 5)
+	first, last, ok = tree.MatchRange(func(v int) bool { return v == 8 })
+	failIfNot(t, ok && first == 3 && last == 3)
+	_, _, ok = tree.MatchRange(func(v int) bool { return v > 10 })
+	failIfNot(t, !ok)
+	first, last, ok = tree.MatchRange(func(v int) bool { return v != 4 })
+	failIfNot(t, ok && first == 0 && last == 7)
+	_, _, ok = newTree[int]().MatchRange(isEven)
+	failIfNot(t, !ok)
+}
+
+func TestPopUntil(t *testing.T) {
+	deadlines := []int{1, 4, 4, 6, 9, 12, 15}
+	tree := FromArray(maxValue(0), deadlines)
+	for now := 0; now <= 16; now++ {
+		due, rest := tree.PopUntil(func(m int) bool { return m > now })
+		left, right := tree.Split(func(m int) bool { return m > now })
+		failIfNot(t, same(due, left.ToSlice()) && same(rest.ToSlice(), right.ToSlice()))
+		failIfNot(t, due != nil && len(due)+rest.Len() == len(deadlines))
+	}
+	// the predicate never fires so everything is popped
+	due, rest := tree.PopUntil(func(m int) bool { return m > 100 })
+	failIfNot(t, same(due, deadlines) && rest.IsEmpty())
+	// the slice is fresh, so changing it does not affect the tree or later pops
+	due[0] = -1
+	again, _ := tree.PopUntil(func(m int) bool { return m > 100 })
+	failIfNot(t, tree.PeekFirst() == 1 && again[0] == 1)
+	due, rest = tree.PopUntil(func(m int) bool { return true })
+	failIfNot(t, len(due) == 0 && rest.Len() == len(deadlines))
+	due, rest = FromArray(maxValue(0), []int{}).PopUntil(func(m int) bool { return m > 3 })
+	failIfNot(t, len(due) == 0 && rest.IsEmpty())
+}
+
+func TestTakeWeight(t *testing.T) {
+	weight := func(v int) float64 { return float64(v) / 2 }
+	tree := newTree(2, 4, 6, 8, 10)
+	taken, rest, left := tree.TakeWeight(7, weight)
+	failIfNot(t, same(taken.ToSlice(), []int{2, 4, 6}) && same(rest.ToSlice(), []int{8, 10}) && left == 1)
+	failIfNot(t, taken.Measure() == 3 && rest.Measure() == 2)
+	taken, rest, left = tree.TakeWeight(100, weight)
+	failIfNot(t, same(taken.ToSlice(), tree.ToSlice()) && rest.IsEmpty() && left == 85)
+	taken, rest, left = tree.TakeWeight(0.5, weight)
+	failIfNot(t, taken.IsEmpty() && rest.Len() == 5 && left == 0.5)
+	taken, rest, left = tree.TakeWeight(3, weight)
+	failIfNot(t, same(taken.ToSlice(), []int{2, 4}) && rest.Len() == 3 && left == 0)
+	taken, _, left = newTree[int]().TakeWeight(1, weight)
+	failIfNot(t, taken.IsEmpty() && left == 1)
+}
+
+func TestAdjacentDuplicates(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+	failIfNot(t, same(newTree(1, 1, 2, 3, 3, 3, 4, 5, 5).AdjacentDuplicates(eq), []int{1, 4, 5, 8}))
+	failIfNot(t, same(newTree(1, 2, 2, 1).AdjacentDuplicates(eq), []int{2}))
+	failIfNot(t, len(newTree(1, 2, 3, 1, 2, 3).AdjacentDuplicates(eq)) == 0)
+	failIfNot(t, len(newTree(7).AdjacentDuplicates(eq)) == 0 && len(newTree[int]().AdjacentDuplicates(eq)) == 0)
+	near := func(a, b int) bool { return a-b <= 1 && b-a <= 1 }
+	failIfNot(t, same(newTree(1, 2, 5, 9, 8).AdjacentDuplicates(near), []int{1, 4}))
+}
+
+func TestTopKBy(t *testing.T) {
+	type pair struct{ key, pos int }
+	values := make([]pair, 500)
+	r := rand.New(rand.NewSource(7))
+	for i := range values {
+		values[i] = pair{r.Intn(40), i}
+	}
+	tree := FromArray(newWidth[pair](), values)
+	// the comparator ignores the measure and pos, so ties must come out in tree order
+	less := func(a, b pair) bool { return a.key < b.key }
+	sorted := tree.ToSlice()
+	slices.SortStableFunc(sorted, func(a, b pair) int { return b.key - a.key })
+	for _, k := range []int{1, 5, 37, 499, 500, 800} {
+		failIfNot(t, same(TopK(tree, k, less), sorted[:min(k, len(sorted))]))
+	}
+	failIfNot(t, TopK(tree, 0, less) == nil && TopK(tree, -1, less) == nil)
+	failIfNot(t, TopK(FromArray(newWidth[pair](), []pair{}), 3, less) == nil)
+}
+
+func TestLongestRun(t *testing.T) {
+	positive := func(v int) bool { return v > 0 }
+	start, length := newTree(1, 0, 2, 3, 0, 4, 5, 6, 0, 7).LongestRun(positive)
+	failIfNot(t, start == 5 && length == 3)
+	start, length = newTree(0, 1, 1, 0, 2, 2, 0, 3).LongestRun(positive)
+	failIfNot(t, start == 1 && length == 2)
+	start, length = newTree(0, 1, 0, 1, 2, 3, 4).LongestRun(positive)
+	failIfNot(t, start == 3 && length == 4)
+	start, length = newTree(1, 2, 3).LongestRun(positive)
+	failIfNot(t, start == 0 && length == 3)
+	_, length = newTree(0, -1, 0).LongestRun(positive)
+	failIfNot(t, length == 0)
+	_, length = newTree[int]().LongestRun(positive)
+	failIfNot(t, length == 0)
+}
+
+func TestFindLast(t *testing.T) {
+	tree := newTree(5, 2, 7, 2, 8, 1)
+	isTwo := func(v int) bool { return v == 2 }
+	v, ok := tree.FindLast(isTwo)
+	failIfNot(t, ok && v == 2 && tree.LastIndexFunc(isTwo) == 3)
+	v, ok = tree.FindLast(func(v int) bool { return v > 6 })
+	failIfNot(t, ok && v == 8)
+	failIfNot(t, tree.LastIndexFunc(func(v int) bool { return v == 5 }) == 0)
+	failIfNot(t, tree.LastIndexFunc(func(v int) bool { return v == 1 }) == 5)
+	_, ok = tree.FindLast(func(v int) bool { return v > 100 })
+	failIfNot(t, !ok && tree.LastIndexFunc(func(v int) bool { return v > 100 }) == -1)
+	_, ok = newTree[int]().FindLast(isTwo)
+	failIfNot(t, !ok && newTree[int]().LastIndexFunc(isTwo) == -1)
+	// finding a value in the right digit does not force the suspended mid
+	suspended, _ := suspendedMid()
+	v, ok = suspended.FindLast(func(v int) bool { return v < 198 })
+	failIfNot(t, ok && v == 197)
+	values := make([]int, 1000)
+	for i := range values {
+		values[i] = i
+	}
+	visited := 0
+	index := newTree(values...).LastIndexFunc(func(v int) bool {
+		visited++
+		return v%100 == 0
+	})
+	failIfNot(t, index == 900 && visited == 100)
+}
+
+func TestIndices(t *testing.T) {
+	tree := newTree(3, 8, 1, 6, 6, 9, 2)
+	failIfNot(t, len(tree.Indices(func(v int) bool { return v > 100 })) == 0)
+	failIfNot(t, same(tree.Indices(func(v int) bool { return v > 0 }), []int{0, 1, 2, 3, 4, 5, 6}))
+	failIfNot(t, same(tree.Indices(func(v int) bool { return v%2 == 0 }), []int{1, 3, 4, 6}))
+	failIfNot(t, len(newTree[int]().Indices(func(int) bool { return true })) == 0)
+	values := make([]int, 1000)
+	for i := range values {
+		values[i] = i
+	}
+	sevens := newTree(values...).Indices(func(v int) bool { return v%7 == 0 })
+	failIfNot(t, len(sevens) == 143 && sevens[142] == 994)
+}
+
+func TestFoldIndexed(t *testing.T) {
+	tree := FromArray(sumValues(0), []int{5, 1, 4, 2})
+	type step struct{ index, v, prefix int }
+	steps := FoldIndexed(tree, []step{}, func(acc []step, index int, v int, prefix int) []step {
+		return append(acc, step{index, v, prefix})
+	})
+	failIfNot(t, same(steps, []step{{0, 5, 5}, {1, 1, 6}, {2, 4, 10}, {3, 2, 12}}))
+	weighted := FoldIndexed(tree, 0, func(acc int, index int, v int, prefix int) int {
+		return acc + index*prefix
+	})
+	failIfNot(t, weighted == 0*5+1*6+2*10+3*12)
+	failIfNot(t, FoldIndexed(FromArray(sumValues(0), []int{}), 7, func(acc, i, v, p int) int { return 0 }) == 7)
+}
+
+func TestFoldMap(t *testing.T) {
+	type span struct{ lo, hi int }
+	identity := span{math.MaxInt, math.MinInt}
+	bounds := func(a, b span) span { return span{min(a.lo, b.lo), max(a.hi, b.hi)} }
+	point := func(v int) span { return span{v, v} }
+	// string concatenation is associative but not commutative, so order matters
+	digits := func(v int) string { return strconv.Itoa(v%10) + "," }
+	concat := func(a, b string) string { return a + b }
+	r := rand.New(rand.NewSource(5))
+	for i := 0; i < 50; i++ {
+		nums := make([]int, r.Intn(300))
+		for j := range nums {
+			nums[j] = r.Intn(2000) - 1000
+		}
+		// split and join to get lazy trees with uneven shapes
+		at := r.Intn(len(nums) + 1)
+		tree := newTree(nums[:at]...).Concat(newTree(nums[at:]...))
+		left, right := tree.Split(func(m int) bool { return m > at/2 })
+		tree = left.Concat(right)
+		expectedSpan, expectedDigits := identity, ""
+		for _, v := range nums {
+			expectedSpan, expectedDigits = bounds(expectedSpan, point(v)), concat(expectedDigits, digits(v))
+		}
+		failIfNot(t, FoldMap(tree, identity, point, bounds) == expectedSpan)
+		failIfNot(t, FoldMap(tree, "", digits, concat) == expectedDigits)
+	}
+	failIfNot(t, FoldMap(newTree[int](), identity, point, bounds) == identity)
+	var zero FingerTree[width[int, int], int, int]
+	failIfNot(t, FoldMap(zero, "none", digits, concat) == "none")
+}
+
+func TestTrim(t *testing.T) {
+	isZero := func(v int) bool { return v == 0 }
+	failIfNot(t, same(newTree(0, 0, 1, 0, 2, 0).Trim(isZero).ToSlice(), []int{1, 0, 2}))
+	failIfNot(t, same(newTree(0, 1, 2).Trim(isZero).ToSlice(), []int{1, 2}))
+	failIfNot(t, same(newTree(1, 2, 0, 0).Trim(isZero).ToSlice(), []int{1, 2}))
+	failIfNot(t, same(newTree(1, 2).Trim(isZero).ToSlice(), []int{1, 2}))
+	failIfNot(t, newTree(0, 0, 0).Trim(isZero).IsEmpty() && newTree[int]().Trim(isZero).IsEmpty())
+	failIfNot(t, same(newTree(0, 1, 0).DropWhile(isZero).ToSlice(), []int{1, 0}))
+	failIfNot(t, same(newTree(0, 1, 0).DropLastWhile(isZero).ToSlice(), []int{0, 1}))
+	failIfNot(t, newTree(0, 0, 3, 0).Trim(isZero).Measure() == 1)
+}
+
+func TestSpan(t *testing.T) {
+	isHeader := func(v int) bool { return v < 0 }
+	header, body := newTree(-3, -2, -1, 4, -5, 6).Span(isHeader)
+	failIfNot(t, same(header.ToSlice(), []int{-3, -2, -1}) && same(body.ToSlice(), []int{4, -5, 6}))
+	failIfNot(t, header.Measure() == 3 && body.Measure() == 3)
+	header, body = newTree(1, 2).Span(isHeader)
+	failIfNot(t, header.IsEmpty() && body.Len() == 2)
+	header, body = newTree(-1, -2).Span(isHeader)
+	failIfNot(t, header.Len() == 2 && body.IsEmpty())
+	header, body = newTree[int]().Span(isHeader)
+	failIfNot(t, header.IsEmpty() && body.IsEmpty())
+	values := make([]int, 1000)
+	for i := range values {
+		values[i] = i - 10
+	}
+	visited := 0
+	header, body = newTree(values...).Span(func(v int) bool {
+		visited++
+		return v < 0
+	})
+	failIfNot(t, visited == 11 && header.Len() == 10 && body.Len() == 990)
+	failIfNot(t, same(header.Concat(body).ToSlice(), values))
+	// the suffix past the first failing value stays lazy
+	lazy := newTree(values[:500]...).Concat(newTree(values[500:]...))
+	header, body = lazy.Span(func(v int) bool { return v < -3 })
+	_, unforced := body.ForceN(0)
+	failIfNot(t, unforced && header.Len() == 7 && same(header.Concat(body).ToSlice(), values))
+	// a boundary in the left digit does not force a suspended mid
+	suspended, broken := suspendedMid()
+	*broken = false
+	prefix, suffix := suspended.Span(func(v int) bool { return v < 3 })
+	failIfNot(t, same(prefix.ToSlice(), []int{1, 2}) && suffix.LazyProfile()[1].Kind == "pending")
+	failIfNot(t, suffix.PeekFirst() == 3 && suffix.Len() == 196 && suffix.Measure() == 196)
+}
+
+func TestDropUntilValue(t *testing.T) {
+	big := func(v int) bool { return v > 5 }
+	failIfNot(t, same(newTree(7, 1, 9).DropUntilValue(big).ToSlice(), []int{7, 1, 9}))
+	rest := newTree(1, 2, 8, 3, 9).DropUntilValue(big)
+	failIfNot(t, same(rest.ToSlice(), []int{8, 3, 9}) && rest.Measure() == 3)
+	failIfNot(t, newTree(1, 2, 3).DropUntilValue(big).IsEmpty())
+	failIfNot(t, newTree[int]().DropUntilValue(big).IsEmpty())
+}
+
+func TestCoalesceAdjacent(t *testing.T) {
+	chunks := []string{"a", "bc", "", "defgh", "i", "j", "klmnopq", "r", "s"}
+	tree := FromArray(newWidth[string](), chunks)
+	small := func(a, b string) bool { return len(a)+len(b) <= 4 }
+	concat := func(a, b string) string { return a + b }
+	merged := tree.CoalesceAdjacent(small, concat)
+	failIfNot(t, same(merged.ToSlice(), []string{"abc", "defgh", "ij", "klmnopq", "rs"}))
+	failIfNot(t, strings.Join(merged.ToSlice(), "") == strings.Join(chunks, ""))
+	failIfNot(t, merged.Measure() == 5 && MeasuresConsistent(merged) == nil)
+	all := tree.CoalesceAdjacent(func(a, b string) bool { return true }, concat)
+	failIfNot(t, same(all.ToSlice(), []string{strings.Join(chunks, "")}))
+	never := tree.CoalesceAdjacent(func(a, b string) bool { return false }, concat)
+	failIfNot(t, same(never.ToSlice(), chunks))
+	single := FromArray(newWidth[string](), []string{"x"})
+	failIfNot(t, same(single.CoalesceAdjacent(small, concat).ToSlice(), []string{"x"}))
+	failIfNot(t, FromArray(newWidth[string](), []string{}).CoalesceAdjacent(small, concat).IsEmpty())
+}
+
+func TestSlidingMax(t *testing.T) {
+	greater := func(a, b int) bool { return a > b }
+	rng := rand.New(rand.NewSource(4))
+	nums := make([]int, 200)
+	for i := range nums {
+		nums[i] = rng.Intn(50)
+	}
+	tree := newTree(nums...)
+	for _, window := range []int{1, 2, 3, 7, 50, 200, 201} {
+		var want []int
+		for start := 0; start+window <= len(nums); start++ {
+			best := nums[start]
+			for _, v := range nums[start : start+window] {
+				best = max(best, v)
+			}
+			want = append(want, best)
+		}
+		failIfNot(t, same(tree.SlidingMax(window, greater), want))
+	}
+	failIfNot(t, same(newTree(1, 3, 2, 5, 4).SlidingMax(2, greater), []int{3, 3, 5, 5}))
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrOutOfRange))
+	}()
+	tree.SlidingMax(0, greater)
+}
+
+func TestEmptyResults(t *testing.T) {
+	tree := newTree(1, 2, 3, 4, 5)
+	failIfNot(t, same(tree.Filter(func(v int) bool { return v%2 == 1 }).ToSlice(), []int{1, 3, 5}))
+	failIfNot(t, same(tree.DeleteRange(1, 3).ToSlice(), []int{1, 4, 5}))
+	failIfNot(t, same(tree.DeleteRange(2, 2).ToSlice(), tree.ToSlice()))
+	for _, emptied := range []FingerTree[width[int, int], int, int]{
+		tree.Filter(func(int) bool { return false }),
+		tree.DeleteRange(0, tree.Len()),
+		newTree[int]().Filter(func(int) bool { return true }),
+		newTree[int]().DeleteRange(0, 0),
+	} {
+		failIfNot(t, !emptied.IsZero() && emptied.IsEmpty() && emptied.Len() == 0 && emptied.Measure() == 0)
+		grown := emptied.AddLast(7).AddFirst(6)
+		failIfNot(t, same(grown.ToSlice(), []int{6, 7}) && grown.Measure() == 2)
+	}
+	strict := FromArrayStrict(newWidth[int](), []int{1, 2, 3})
+	failIfNot(t, strict.Filter(func(int) bool { return false }).IsStrict())
+	failIfNot(t, strict.DeleteRange(0, 3).IsStrict())
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrOutOfRange))
+	}()
+	tree.DeleteRange(3, 6)
+}
+
+func TestSuspensionPanic(t *testing.T) {
+	DebugSuspensions = true
+	defer func() { DebugSuspensions = false }()
+	broken := false
+	meas := slowWidth{panic: &broken}
+	tree := FromArray(meas, []int{})
+	for i := 0; i < 40; i++ {
+		tree = tree.Concat(FromArray(meas, []int{1, 2, 3, 4, 5, 6, 7}))
+	}
+	broken = true
+	defer func() {
+		p, ok := recover().(*SuspensionPanic)
+		failIfNot(t, ok && p.Op == "Concat" && errors.Is(p, ErrBadValue) && errors.Is(p, ErrFingerTree))
+		failIfNot(t, ok && strings.Contains(p.Caller, "main_test.go:"))
+		failIfNot(t, ok && strings.Contains(p.Error(), "created by Concat at "))
+	}()
+	tree.ToSlice()
+	t.Fatal("expected a panic")
+}
+
+// A width measurer that counts its evaluations
+type countingWidth struct {
+	count *int
+}
+
+func (m countingWidth) Identity() int {
+	return 0
+}
+
+func (m countingWidth) Measure(v int) int {
+	*m.count++
+	return 1
+}
+
+func (m countingWidth) Sum(a, b int) int {
+	*m.count++
+	return a + b
+}
+
+func TestConcatMany(t *testing.T) {
+	var expected []int
+	var trees []FingerTree[width[int, int], int, int]
+	for i := 0; i < 50; i++ {
+		values := make([]int, i%7*i)
+		for j := range values {
+			values[j] = len(expected) + j
+		}
+		expected = append(expected, values...)
+		trees = append(trees, newTree(values...))
+		if i%10 == 0 {
+			trees = append(trees, FingerTree[width[int, int], int, int]{})
+		}
+	}
+	joined := Concat(trees...)
+	failIfNot(t, same(joined.ToSlice(), expected) && joined.Measure() == len(expected))
+	failIfErrNow(t, MeasuresConsistent(joined))
+	a := newTree(1, 2, 3)
+	failIfNot(t, Concat(a).f == a.f)
+	failIfNot(t, same(Concat(newTree[int](), a, newTree[int]()).ToSlice(), []int{1, 2, 3}))
+	failIfNot(t, Concat[width[int, int]]().IsZero() && Concat(newTree[int]()).IsEmpty())
+	strict := Concat(FromArrayStrict(newWidth[int](), []int{0}), a, newTree(4, 5))
+	failIfNot(t, strict.IsStrict() && same(strict.ToSlice(), []int{0, 1, 2, 3, 4, 5}))
+}
+
+func benchmarkConcat(b *testing.B, count int) {
+	trees := make([]FingerTree[width[int, int], int, int], count)
+	for i := range trees {
+		// mixed sizes, mostly small with the occasional big tree
+		values := make([]int, []int{1, 3, 20, 1, 400}[i%5])
+		trees[i] = newTree(values...)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		joined, _ := Concat(trees...).ForceN(math.MaxInt)
+		if joined.IsEmpty() {
+			b.Fatal("empty result")
+		}
+	}
+}
+
+func TestSuspensionCount(t *testing.T) {
+	tree := newTree(0, 1, 2, 3, 4, 5, 6, 7)
+	for i := 1; i < 10; i++ {
+		tree = tree.Concat(newTree(8*i, 8*i+1, 8*i+2, 8*i+3, 8*i+4, 8*i+5, 8*i+6, 8*i+7))
+	}
+	// each join after the first is suspended inside the one after it
+	profile := tree.LazyProfile()
+	failIfNot(t, len(profile) == 2 && profile[1].Debt == 9 && tree.SuspensionCount() == 9)
+	tree.ToSlice()
+	failIfNot(t, tree.SuspensionCount() == 0)
+}
+
+func TestConcatDebt(t *testing.T) {
+	count := 0
+	meas := countingWidth{&count}
+	tree := FromArray(meas, []int{})
+	var expected []int
+	worst := 0
+	for i := 0; i < 3000; i++ {
+		piece := []int{i * 3, i*3 + 1, i*3 + 2}
+		expected = append(expected, piece...)
+		count = 0
+		tree = tree.Concat(FromArray(meas, piece))
+		// the measure is available without normalizing the appends
+		failIfNot(t, tree.Measure() == len(expected) && tree.Len() == len(expected))
+		worst = max(worst, count)
+	}
+	// the pending joins hide inside one suspension but are still counted
+	failIfNot(t, tree.SuspensionCount() > 1 && tree.SuspensionCount() <= maxConcatDebt)
+	// without a bound on the deferred appends, this first Get pays for thousands of them
+	count = 0
+	failIfNot(t, tree.Get(4500) == 4500)
+	worst = max(worst, count)
+	failIfNot(t, worst < 1000)
+	failIfNot(t, same(tree.ToSlice(), expected))
+}
+
+func BenchmarkConcatPieces(b *testing.B) {
+	pieces := make([]FingerTree[width[int, int], int, int], 1000)
+	for i := range pieces {
+		pieces[i] = newTree(i*3, i*3+1, i*3+2)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree := newTree[int]()
+		for _, piece := range pieces {
+			tree = tree.Concat(piece)
+		}
+		if tree.Get(1500) != 1500 {
+			b.Fatal("wrong value")
+		}
+	}
+}
+
+func BenchmarkConcat2(b *testing.B) {
+	benchmarkConcat(b, 2)
+}
+
+func BenchmarkConcat10(b *testing.B) {
+	benchmarkConcat(b, 10)
+}
+
+func BenchmarkConcat1000(b *testing.B) {
+	benchmarkConcat(b, 1000)
+}
+
+func TestLazyProfile(t *testing.T) {
+	values := make([]int, 100)
+	part := newTree(values...)
+	failIfNot(t, part.SuspensionCount() == 0)
+	profile := part.LazyProfile()
+	failIfNot(t, len(profile) > 1 && profile[0].Kind == "deep" && profile[len(profile)-1].Kind != "pending")
+	joined := part.Concat(part).Concat(part)
+	profile = joined.LazyProfile()
+	// concatenating suspends the mid tree of the result but still measures it
+	failIfNot(t, len(profile) == 2 && profile[0].Kind == "deep" && profile[0].Measured)
+	failIfNot(t, profile[1].Kind == "pending" && profile[1].Op == "Concat" && profile[1].Depth == 1)
+	// the second join is suspended inside the first, so it is counted without showing
+	failIfNot(t, profile[1].Debt == 2 && joined.SuspensionCount() == 2)
+	var before strings.Builder
+	joined.DumpLazy(&before)
+	failIfNot(t, before.String() == "0: deep left 3 right 1\n  1: pending suspension from Concat with 2 unforced suspensions\n")
+	// introspection does not force anything
+	failIfNot(t, joined.SuspensionCount() == 2)
+	joined.ForceN(1)
+	profile = joined.LazyProfile()
+	failIfNot(t, len(profile) == 3 && profile[1].Kind == "deep" && profile[1].Forced == 1)
+	failIfNot(t, profile[2].Kind == "pending" && joined.SuspensionCount() == profile[2].Debt)
+	// the measure and length are known without forcing, but reaching a value forces
+	failIfNot(t, joined.Len() == 300 && joined.SuspensionCount() == profile[2].Debt)
+	failIfNot(t, len(joined.ToSlice()) == 300 && joined.SuspensionCount() == 0)
+	profile = joined.LazyProfile()
+	failIfNot(t, profile[0].Measured && profile[len(profile)-1].Kind == "empty")
+	var after strings.Builder
+	joined.DumpLazy(&after)
+	failIfNot(t, strings.Contains(after.String(), "  1: deep left 4 right 1 forced suspension from Concat\n"))
+	failIfNot(t, !strings.Contains(after.String(), "pending"))
+	var zero FingerTree[width[int, int], int, int]
+	failIfNot(t, zero.SuspensionCount() == 0 && len(zero.LazyProfile()) == 1)
+}
+
+func TestLevelMeasures(t *testing.T) {
+	tree := FromArray(sumValues(0), []int{})
+	for i := 1; i <= 12; i++ {
+		tree = tree.AddLast(i)
+	}
+	// appending pushes nodes of 3 into the mid tree
+	failIfNot(t, same(tree.LevelMeasures(0), []int{1, 9, 18, 27, 11, 12}))
+	failIfNot(t, same(tree.LevelMeasures(1), []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}))
+	values := make([]int, 2000)
+	for i := range values {
+		values[i] = i
+	}
+	big := FromArray(sumValues(0), values)
+	prev := 0
+	for level := 0; ; level++ {
+		measures := big.LevelMeasures(level)
+		total := 0
+		for _, m := range measures {
+			total += m
+		}
+		failIfNot(t, total == big.Measure() && len(measures) > prev)
+		prev = len(measures)
+		if len(measures) == len(values) {
+			failIfNot(t, same(measures, values) && level > 2)
+			break
+		}
+	}
+	failIfNot(t, len(newTree[int]().LevelMeasures(0)) == 0)
+	failIfNot(t, same(newTree(5).LevelMeasures(3), []int{1}))
+}
+
+func TestForceN(t *testing.T) {
+	build := func(count *int) FingerTree[countingWidth, int, int] {
+		nums := make([]int, 1000)
+		for i := range nums {
+			nums[i] = i
+		}
+		tree := FromArray(countingWidth{count}, nums)
+		for i := 0; i < 300; i++ {
+			tree = tree.RemoveFirst().RemoveLast()
+		}
+		return tree
+	}
+	want := make([]int, 400)
+	for i := range want {
+		want[i] = i + 300
+	}
+	total := 0
+	full := build(&total)
+	total = 0
+	full, more := full.ForceN(math.MaxInt)
+	_, unforced := full.ForceN(0)
+	failIfNot(t, !more && !unforced && total > 0)
+	count := 0
+	tree := build(&count)
+	count = 0
+	steps := 0
+	for more = true; more; steps++ {
+		tree, more = tree.ForceN(1)
+		partial := 0
+		check, _ := build(&partial).ForceN(steps + 1)
+		failIfNot(t, same(check.ToSlice(), want))
+	}
+	// each suspension was forced once, so the work adds up to forcing all at once
+	failIfNot(t, steps > 2 && count == total)
+	again, more := tree.ForceN(10)
+	failIfNot(t, !more && count == total && same(again.ToSlice(), want))
+}
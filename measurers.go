@@ -0,0 +1,270 @@
+package lazyfingertree
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Number is the set of types SumMeasurer can add up.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// SumMeasurer measures numbers by themselves, so a tree's measure is the sum of its values.
+type SumMeasurer[N Number] struct{}
+
+func (m SumMeasurer[N]) Identity() N {
+	return 0
+}
+
+func (m SumMeasurer[N]) Measure(value N) N {
+	return value
+}
+
+func (m SumMeasurer[N]) Sum(a N, b N) N {
+	return a + b
+}
+
+func (m SumMeasurer[N]) Inverse(measure N) N {
+	return -measure
+}
+
+// Add delta to the value at index, returning the updated tree. A tree of numbers
+// measured by their sum works like a persistent Fenwick tree: this updates every
+// affected prefix measure in O(log n) and the old tree is unchanged. Prefix sums
+// come from splitting at an index and, if the values are not negative, Split with
+// a "sum > target" predicate finds the first index where the prefix sum passes a
+// target. This panics if index is out of range.
+func PointUpdate[N Number](t FingerTree[SumMeasurer[N], N, N], index int, delta N) FingerTree[SumMeasurer[N], N, N] {
+	if index < 0 || index >= t.tree().size() {
+		panic(fmt.Errorf("%w: %d in tree of length %d", ErrOutOfRange, index, t.tree().size()))
+	}
+	left, right := splitAt(t.tree(), index)
+	value := right.PeekFirst().(N)
+	return wrapTree[SumMeasurer[N], N, N](left.AddLast(value + delta).Concat(right.RemoveFirst()))
+}
+
+// NoMeasurer is for trees used only as persistent sequences and deques, which do not
+// need measures. Trees recognize it and replace it with an internal measurer whose
+// measures are all struct{}, so they skip the calls through the Measurer interface.
+// Digits, nodes, and deep trees still combine measurements to track their sizes, so
+// only that dispatch is saved. Indexing and splitting by position work
+// but Split and the other measure-based operations cannot find anything because
+// every measure is the same.
+type NoMeasurer[V any] struct{}
+
+func (m NoMeasurer[V]) Identity() struct{} {
+	return struct{}{}
+}
+
+func (m NoMeasurer[V]) Measure(value V) struct{} {
+	return struct{}{}
+}
+
+func (m NoMeasurer[V]) Sum(a struct{}, b struct{}) struct{} {
+	return struct{}{}
+}
+
+// The internal measurer for NoMeasurer trees, which never calls the user measurer
+type unmeasured struct {
+	user any
+}
+
+func (m unmeasured) Identity() any {
+	return struct{}{}
+}
+
+func (m unmeasured) Measure(value any) any {
+	return struct{}{}
+}
+
+func (m unmeasured) Sum(a any, b any) any {
+	return struct{}{}
+}
+
+func (m unmeasured) userMeasurer() any {
+	return m.user
+}
+
+// A GroupMeasurer is a Measurer whose measures can be subtracted: Sum(m, Inverse(m))
+// is the identity.
+type GroupMeasurer[Value, Measure any] interface {
+	Measurer[Value, Measure]
+	Inverse(measure Measure) Measure
+}
+
+// Return the tree's measure without the measures of values, which are typically
+// values external code has removed from what the tree represents. This subtracts
+// their measures from the total so it returns an ErrUnsupported error unless the
+// tree's measurer is a GroupMeasurer.
+func (t FingerTree[MS, V, M]) MeasureExcluding(values []V) (M, error) {
+	group, ok := userMeasurer(measurerFor(t.tree())).(GroupMeasurer[V, M])
+	if !ok {
+		return null[M](), fmt.Errorf("%w: MeasureExcluding requires a GroupMeasurer, not %T", ErrUnsupported, t.measurer())
+	}
+	result := t.Measure()
+	for _, v := range values {
+		result = group.Sum(result, group.Inverse(group.Measure(v)))
+	}
+	return result, nil
+}
+
+// Return a measurer that sums the named integer fields of struct values, for
+// prototyping composite measures without writing a Measurer by hand. V can be a
+// struct or a pointer to one, and fields can be promoted from embedded structs. Each
+// measure maps the field names to their sums. This returns an ErrBadMeasurer error
+// if a field does not exist or is not an integer.
+//
+// This uses reflection and allocates a map for every measure so it is much slower
+// than a hand-written measurer. Field lookups are done once here, so measuring a
+// value costs one FieldByIndex per field. Nil pointers, including nil embedded
+// pointers along a field's path, count as zero.
+func StructMeasurer[V any](fields ...string) (Measurer[V, map[string]int64], error) {
+	typ := reflect.TypeFor[V]()
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: StructMeasurer needs a struct or struct pointer type, not %v", ErrBadMeasurer, reflect.TypeFor[V]())
+	} else if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: StructMeasurer needs at least one field", ErrBadMeasurer)
+	}
+	m := structMeasurer[V]{fields: fields, indexes: make([][]int, len(fields))}
+	for i, name := range fields {
+		field, ok := typ.FieldByName(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v has no field %s", ErrBadMeasurer, typ, name)
+		}
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			return nil, fmt.Errorf("%w: field %s of %v is %v, not an integer", ErrBadMeasurer, name, typ, field.Type)
+		}
+		for _, prev := range fields[:i] {
+			if prev == name {
+				return nil, fmt.Errorf("%w: duplicate field %s", ErrBadMeasurer, name)
+			}
+		}
+		m.indexes[i] = field.Index
+	}
+	return m, nil
+}
+
+type structMeasurer[V any] struct {
+	fields  []string
+	indexes [][]int
+}
+
+func (m structMeasurer[V]) Identity() map[string]int64 {
+	result := make(map[string]int64, len(m.fields))
+	for _, name := range m.fields {
+		result[name] = 0
+	}
+	return result
+}
+
+func (m structMeasurer[V]) Measure(value V) map[string]int64 {
+	result := m.Identity()
+	v := reflect.ValueOf(&value).Elem()
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return result
+		}
+		v = v.Elem()
+	}
+	for i, index := range m.indexes {
+		field, err := v.FieldByIndexErr(index)
+		if err != nil {
+			// a nil embedded pointer
+			continue
+		} else if field.CanInt() {
+			result[m.fields[i]] = field.Int()
+		} else {
+			result[m.fields[i]] = int64(field.Uint())
+		}
+	}
+	return result
+}
+
+func (m structMeasurer[V]) Sum(a map[string]int64, b map[string]int64) map[string]int64 {
+	result := make(map[string]int64, len(m.fields))
+	for _, name := range m.fields {
+		result[name] = a[name] + b[name]
+	}
+	return result
+}
+
+// A TraceEvent records one call a tree made to a measurer, see [TracingMeasurer].
+type TraceEvent struct {
+	Seq    uint64 // the call's position in the trace, starting at 1
+	Op     string // "Identity", "Measure", or "Sum"
+	Args   []any  // the value for Measure, the two measures for Sum
+	Result any
+}
+
+// Return the event as a call, like "#3 Sum(1, 2) = 3".
+func (e TraceEvent) String() string {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = fmt.Sprint(arg)
+	}
+	return fmt.Sprintf("#%d %s(%s) = %v", e.Seq, e.Op, strings.Join(args, ", "), e.Result)
+}
+
+// Return a measurer that forwards to inner and reports each call to sink, for
+// debugging measurers. Results are unchanged. The sink is called synchronously
+// from whichever goroutine uses the tree.
+func TracingMeasurer[V, M any](inner Measurer[V, M], sink func(event TraceEvent)) Measurer[V, M] {
+	return &tracingMeasurer[V, M]{inner: inner, sink: sink}
+}
+
+type tracingMeasurer[V, M any] struct {
+	inner Measurer[V, M]
+	sink  func(event TraceEvent)
+	seq   atomic.Uint64
+}
+
+func (m *tracingMeasurer[V, M]) Identity() M {
+	result := m.inner.Identity()
+	m.sink(TraceEvent{m.seq.Add(1), "Identity", nil, result})
+	return result
+}
+
+func (m *tracingMeasurer[V, M]) Measure(value V) M {
+	result := m.inner.Measure(value)
+	m.sink(TraceEvent{m.seq.Add(1), "Measure", []any{value}, result})
+	return result
+}
+
+func (m *tracingMeasurer[V, M]) Sum(a M, b M) M {
+	result := m.inner.Sum(a, b)
+	m.sink(TraceEvent{m.seq.Add(1), "Sum", []any{a, b}, result})
+	return result
+}
+
+// Return a trace sink that appends events to *events, for test assertions.
+func RecordTrace(events *[]TraceEvent) func(event TraceEvent) {
+	var lock sync.Mutex
+	return func(event TraceEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		*events = append(*events, event)
+	}
+}
+
+// Return a trace sink that writes each event to w on its own line.
+func WriteTrace(w io.Writer) func(event TraceEvent) {
+	var lock sync.Mutex
+	return func(event TraceEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		fmt.Fprintln(w, event)
+	}
+}
@@ -0,0 +1,165 @@
+package lazyfingertree
+
+import "container/heap"
+
+// A Multiset is a persistent multiset that stores each distinct key once with its
+// count, sorted by key. The measure tracks the largest key, the total count, and the
+// largest count, so lookups take one descent and TopK can skip subtrees whose counts
+// are too small to matter.
+type Multiset[K any] struct {
+	tree FingerTree[countMeasurer[K], KeyCount[K], countMeasure[K]]
+}
+
+// A KeyCount is a key in a Multiset and the number of times it occurs.
+type KeyCount[K any] struct {
+	Key   K
+	Count int
+}
+
+type countMeasure[K any] struct {
+	key      K // the largest key
+	total    int
+	maxCount int
+	valid    bool
+}
+
+type countMeasurer[K any] struct {
+	compare func(a, b K) int
+}
+
+func (m countMeasurer[K]) Identity() countMeasure[K] {
+	return countMeasure[K]{}
+}
+
+func (m countMeasurer[K]) Measure(kc KeyCount[K]) countMeasure[K] {
+	return countMeasure[K]{kc.Key, kc.Count, kc.Count, true}
+}
+
+func (m countMeasurer[K]) Sum(a countMeasure[K], b countMeasure[K]) countMeasure[K] {
+	if !a.valid {
+		return b
+	} else if !b.valid {
+		return a
+	}
+	result := b
+	if m.compare(a.key, b.key) > 0 {
+		result.key = a.key
+	}
+	result.total = a.total + b.total
+	result.maxCount = max(a.maxCount, b.maxCount)
+	return result
+}
+
+// Create an empty multiset that orders keys with compare.
+func NewMultiset[K any](compare func(a, b K) int) Multiset[K] {
+	return Multiset[K]{FromArray(countMeasurer[K]{compare}, []KeyCount[K]{})}
+}
+
+// Return the number of distinct keys.
+func (s Multiset[K]) Len() int {
+	return s.tree.Len()
+}
+
+// Return the sum of all the counts.
+func (s Multiset[K]) Total() int {
+	return s.tree.Measure().total
+}
+
+// Return the keys and their counts in key order.
+func (s Multiset[K]) Entries() []KeyCount[K] {
+	return s.tree.ToSlice()
+}
+
+func (s Multiset[K]) splitKey(key K) (FingerTree[countMeasurer[K], KeyCount[K], countMeasure[K]], FingerTree[countMeasurer[K], KeyCount[K], countMeasure[K]]) {
+	compare := s.tree.measurer().compare
+	return s.tree.Split(func(m countMeasure[K]) bool {
+		return m.valid && compare(m.key, key) >= 0
+	})
+}
+
+// Return the number of times key occurs.
+func (s Multiset[K]) Count(key K) int {
+	_, right := s.splitKey(key)
+	if right.IsEmpty() || s.tree.measurer().compare(right.PeekFirst().Key, key) != 0 {
+		return 0
+	}
+	return right.PeekFirst().Count
+}
+
+// Return a multiset with count added to key's count. Keys whose counts drop to zero
+// or below are removed.
+func (s Multiset[K]) Add(key K, count int) Multiset[K] {
+	left, right := s.splitKey(key)
+	if !right.IsEmpty() && s.tree.measurer().compare(right.PeekFirst().Key, key) == 0 {
+		count += right.PeekFirst().Count
+		right = right.RemoveFirst()
+	}
+	if count > 0 {
+		left = left.AddLast(KeyCount[K]{key, count})
+	}
+	return Multiset[K]{left.Concat(right)}
+}
+
+// Return the k keys with the largest counts, largest first. Equal counts are in key
+// order. This makes one pass, skipping subtrees whose largest count cannot make the
+// cut, and keeps at most k entries in a heap.
+func (s Multiset[K]) TopK(k int) []KeyCount[K] {
+	if k <= 0 || s.tree.IsEmpty() {
+		return nil
+	}
+	best := &countHeap[K]{}
+	keep := wrapPredicate(func(m countMeasure[K]) bool {
+		// keys come in order, so a later key must beat the k-th count to replace it
+		return len(best.items) < k || m.maxCount > best.items[0].Count
+	})
+	eachPruned(measurerFor(s.tree.f), s.tree.f, keep, func(v any) bool {
+		heap.Push(best, v.(KeyCount[K]))
+		if len(best.items) > k {
+			heap.Pop(best)
+		}
+		return true
+	})
+	result := make([]KeyCount[K], len(best.items))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(best).(KeyCount[K])
+	}
+	return result
+}
+
+// A min-heap of the best entries seen so far: the top is the smallest count, with the
+// latest key breaking ties. The sequence numbers record key order.
+type countHeap[K any] struct {
+	items []KeyCount[K]
+	seqs  []int
+	next  int
+}
+
+func (h *countHeap[K]) Len() int {
+	return len(h.items)
+}
+
+func (h *countHeap[K]) Less(i, j int) bool {
+	if h.items[i].Count != h.items[j].Count {
+		return h.items[i].Count < h.items[j].Count
+	}
+	return h.seqs[i] > h.seqs[j]
+}
+
+func (h *countHeap[K]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.seqs[i], h.seqs[j] = h.seqs[j], h.seqs[i]
+}
+
+func (h *countHeap[K]) Push(x any) {
+	h.items = append(h.items, x.(KeyCount[K]))
+	h.seqs = append(h.seqs, h.next)
+	h.next++
+}
+
+func (h *countHeap[K]) Pop() any {
+	last := len(h.items) - 1
+	result := h.items[last]
+	h.items = h.items[:last]
+	h.seqs = h.seqs[:last]
+	return result
+}
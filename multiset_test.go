@@ -0,0 +1,46 @@
+package lazyfingertree
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestMultiset(t *testing.T) {
+	set := NewMultiset(compareInts)
+	set = set.Add(3, 2).Add(1, 1).Add(3, 1).Add(2, 5).Add(1, -1)
+	failIfNot(t, set.Len() == 2 && set.Total() == 8)
+	failIfNot(t, set.Count(3) == 3 && set.Count(2) == 5 && set.Count(1) == 0 && set.Count(9) == 0)
+	failIfNot(t, slices.Equal(set.Entries(), []KeyCount[int]{{2, 5}, {3, 3}}))
+}
+
+func TestTopK(t *testing.T) {
+	rng := rand.New(rand.NewSource(17))
+	set := NewMultiset(compareInts)
+	counts := map[int]int{}
+	for i := 0; i < 2000; i++ {
+		key := rng.Intn(300)
+		count := 1
+		if key%50 == 0 {
+			count = 20
+		}
+		set = set.Add(key, count)
+		counts[key] += count
+	}
+	all := []KeyCount[int]{}
+	for key, count := range counts {
+		all = append(all, KeyCount[int]{key, count})
+	}
+	slices.SortFunc(all, func(a, b KeyCount[int]) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return a.Key - b.Key
+	})
+	for _, k := range []int{1, 3, 10, 50, len(all), len(all) + 10} {
+		failIfNot(t, slices.Equal(set.TopK(k), all[:min(k, len(all))]))
+	}
+	failIfNot(t, set.TopK(0) == nil && NewMultiset(compareInts).TopK(3) == nil)
+	ties := NewMultiset(compareInts).Add(5, 2).Add(1, 2).Add(3, 2).Add(4, 1)
+	failIfNot(t, slices.Equal(ties.TopK(2), []KeyCount[int]{{1, 2}, {3, 2}}))
+}
@@ -8,6 +8,7 @@ import (
 // A node is a measured container of either 2 or 3 sub-finger-trees.
 type node struct {
 	_measurement measurement
+	_size        int
 	children     []any
 }
 
@@ -21,10 +22,12 @@ func asNode(v any) *node {
 
 func newNode(measurer measurer, items []any) *node {
 	m := measurer.Identity()
+	size := 0
 	for _, item := range items {
 		m = measurer.Sum(m, measurer.Measure(item))
+		size += sizeOf(item)
 	}
-	return &node{measurement{measurer, m}, items}
+	return &node{measurement{measurer, m}, size, items}
 }
 
 func (n *node) String() string {
@@ -44,7 +47,7 @@ func (n *node) String() string {
 }
 
 func (n *node) toDigit() *digit {
-	return &digit{n._measurement, n.children}
+	return &digit{n._measurement, n._size, n.children}
 }
 
 func (n *node) Each(f iterFunc) bool {
@@ -0,0 +1,148 @@
+package lazyfingertree
+
+import "fmt"
+
+// A KeyMeasurer measures values in a tree that is sorted by key. The measure of a
+// sequence of values is its largest key, which lets splits and searches find keys.
+type KeyMeasurer[V, K any] struct {
+	// Return the key for a value
+	Key func(V) K
+	// Return a negative number if a < b, zero if a == b, or a positive number if a > b
+	Compare func(a, b K) int
+}
+
+// A KeyMeasure is the largest key in a sequence. Valid is false for empty sequences.
+type KeyMeasure[K any] struct {
+	Key   K
+	Valid bool
+}
+
+// A KeyedTree is a tree sorted by key
+type KeyedTree[V, K any] = FingerTree[KeyMeasurer[V, K], V, KeyMeasure[K]]
+
+func (m KeyMeasurer[V, K]) Identity() KeyMeasure[K] {
+	return KeyMeasure[K]{}
+}
+
+func (m KeyMeasurer[V, K]) Measure(value V) KeyMeasure[K] {
+	return KeyMeasure[K]{m.Key(value), true}
+}
+
+func (m KeyMeasurer[V, K]) Sum(a KeyMeasure[K], b KeyMeasure[K]) KeyMeasure[K] {
+	if !a.Valid || (b.Valid && m.Compare(a.Key, b.Key) <= 0) {
+		return b
+	}
+	return a
+}
+
+// Return a predicate that is true once a measure's key is at least key.
+func (m KeyMeasurer[V, K]) AtLeast(key K) Predicate[KeyMeasure[K]] {
+	return func(km KeyMeasure[K]) bool {
+		return km.Valid && m.Compare(km.Key, key) >= 0
+	}
+}
+
+// Return a predicate that is true once a measure's key is greater than key.
+func (m KeyMeasurer[V, K]) Above(key K) Predicate[KeyMeasure[K]] {
+	return func(km KeyMeasure[K]) bool {
+		return km.Valid && m.Compare(km.Key, key) > 0
+	}
+}
+
+var ErrDuplicateKey = fmt.Errorf("%w, duplicate key", ErrFingerTree)
+
+// Insert value after any values with an equal key, so equal keys keep their
+// insertion order like a multiset.
+func InsertOrdered[V, K any](t KeyedTree[V, K], value V) KeyedTree[V, K] {
+	result, _, _ := insertOrdered(t, value, insertAfter)
+	return result
+}
+
+// Insert value, replacing the value with an equal key if there is one, and return
+// whether it replaced one. If there are several, this replaces the last of them.
+func UpsertOrdered[V, K any](t KeyedTree[V, K], value V) (KeyedTree[V, K], bool) {
+	result, found, _ := insertOrdered(t, value, replaceEqual)
+	return result, found
+}
+
+// Insert value unless a value with an equal key is present, in which case this
+// returns t and an ErrDuplicateKey error. This checks for the key during the insert
+// so it costs no more than InsertOrdered.
+func InsertOrderedUnique[V, K any](t KeyedTree[V, K], value V) (KeyedTree[V, K], error) {
+	result, _, err := insertOrdered(t, value, rejectEqual)
+	return result, err
+}
+
+// What to do when inserting a key that is already present
+type duplicatePolicy int
+
+const (
+	insertAfter duplicatePolicy = iota
+	replaceEqual
+	rejectEqual
+)
+
+func insertOrdered[V, K any](t KeyedTree[V, K], value V, policy duplicatePolicy) (KeyedTree[V, K], bool, error) {
+	key := t.measurer().Key(value)
+	notAbove, above, found := splitAtKey(t, key)
+	if found {
+		switch policy {
+		case replaceEqual:
+			notAbove = notAbove.RemoveLast()
+		case rejectEqual:
+			return t, true, fmt.Errorf("%w: %v", ErrDuplicateKey, key)
+		}
+	}
+	return notAbove.AddLast(value).Concat(above), found, nil
+}
+
+// Split t after the last value with a key <= key, in one descent, and return
+// whether that value's key equals key. All the ordered insertions use this so they
+// treat runs of equal keys the same way.
+func splitAtKey[V, K any](t KeyedTree[V, K], key K) (KeyedTree[V, K], KeyedTree[V, K], bool) {
+	meas := t.measurer()
+	notAbove, above := t.Split(meas.Above(key))
+	found := !notAbove.IsEmpty() && meas.Compare(meas.Key(notAbove.PeekLast()), key) == 0
+	return notAbove, above, found
+}
+
+// Return the measurer a tree was created with.
+func (t FingerTree[MS, V, M]) measurer() MS {
+	if t.f == nil {
+		panic(fmt.Errorf("%w: cannot get its measurer", ErrZeroTree))
+	}
+	return userMeasurer(measurerFor(t.tree())).(MS)
+}
+
+// Return the first value in t for which pred is true of the prefix measure ending
+// with it, along with the value before it, in one descent.
+func locateKey[V, K any](t KeyedTree[V, K], pred Predicate[KeyMeasure[K]]) (location, bool) {
+	p := wrapPredicate(pred)
+	if !p(t.tree().measurement().value) {
+		return location{}, false
+	}
+	return locate(t.tree(), p, t.measurer().Identity()), true
+}
+
+// Return the largest value with a key <= key, in one descent.
+func Floor[V, K any](t KeyedTree[V, K], key K) (V, bool) {
+	loc, ok := locateKey(t, t.measurer().Above(key))
+	if !ok {
+		if t.IsEmpty() {
+			return null[V](), false
+		}
+		return t.PeekLast(), true
+	} else if !loc.hasPrev {
+		return null[V](), false
+	}
+	return loc.prev.(V), true
+}
+
+// Return the smallest value with a key >= key, in one descent.
+func Ceiling[V, K any](t KeyedTree[V, K], key K) (V, bool) {
+	loc, ok := locateKey(t, t.measurer().AtLeast(key))
+	if !ok {
+		return null[V](), false
+	}
+	return loc.value.(V), true
+}
@@ -0,0 +1,117 @@
+package lazyfingertree
+
+import (
+	"errors"
+	"testing"
+)
+
+func compareInts(a, b int) int {
+	return a - b
+}
+
+func intKeys() KeyMeasurer[int, int] {
+	return KeyMeasurer[int, int]{func(v int) int { return v }, compareInts}
+}
+
+func keyedInts(values ...int) KeyedTree[int, int] {
+	return FromArray(intKeys(), values)
+}
+
+func TestFloorCeiling(t *testing.T) {
+	values := []int{}
+	for i := 10; i <= 500; i += 10 {
+		values = append(values, i)
+	}
+	tree := keyedInts(values...)
+	for key := 0; key <= 510; key++ {
+		floor, floorOk := Floor(tree, key)
+		ceiling, ceilingOk := Ceiling(tree, key)
+		switch {
+		case key < 10:
+			failIfNot(t, !floorOk && ceilingOk && ceiling == 10)
+		case key > 500:
+			failIfNot(t, floorOk && floor == 500 && !ceilingOk)
+		case key%10 == 0:
+			failIfNot(t, floorOk && ceilingOk && floor == key && ceiling == key)
+		default:
+			failIfNot(t, floorOk && ceilingOk && floor == key/10*10 && ceiling == key/10*10+10)
+		}
+	}
+	_, ok := Floor(keyedInts(), 5)
+	failIfNot(t, !ok)
+	_, ok = Ceiling(keyedInts(), 5)
+	failIfNot(t, !ok)
+}
+
+type tagged struct {
+	key int
+	tag string
+}
+
+func taggedTree(compares *int, values ...tagged) KeyedTree[tagged, int] {
+	return FromArray(KeyMeasurer[tagged, int]{
+		func(v tagged) int { return v.key },
+		func(a, b int) int {
+			*compares++
+			return a - b
+		},
+	}, values)
+}
+
+func TestOrderedInsertPolicies(t *testing.T) {
+	compares := 0
+	tree := taggedTree(&compares, tagged{1, "a"}, tagged{3, "a"}, tagged{3, "b"}, tagged{5, "a"})
+	for _, test := range []struct {
+		value    tagged
+		inserted []tagged
+		upserted []tagged
+		found    bool
+	}{
+		{tagged{0, "new"},
+			[]tagged{{0, "new"}, {1, "a"}, {3, "a"}, {3, "b"}, {5, "a"}},
+			[]tagged{{0, "new"}, {1, "a"}, {3, "a"}, {3, "b"}, {5, "a"}},
+			false},
+		{tagged{3, "new"},
+			[]tagged{{1, "a"}, {3, "a"}, {3, "b"}, {3, "new"}, {5, "a"}},
+			[]tagged{{1, "a"}, {3, "a"}, {3, "new"}, {5, "a"}},
+			true},
+		{tagged{1, "new"},
+			[]tagged{{1, "a"}, {1, "new"}, {3, "a"}, {3, "b"}, {5, "a"}},
+			[]tagged{{1, "new"}, {3, "a"}, {3, "b"}, {5, "a"}},
+			true},
+		{tagged{5, "new"},
+			[]tagged{{1, "a"}, {3, "a"}, {3, "b"}, {5, "a"}, {5, "new"}},
+			[]tagged{{1, "a"}, {3, "a"}, {3, "b"}, {5, "new"}},
+			true},
+		{tagged{4, "new"},
+			[]tagged{{1, "a"}, {3, "a"}, {3, "b"}, {4, "new"}, {5, "a"}},
+			[]tagged{{1, "a"}, {3, "a"}, {3, "b"}, {4, "new"}, {5, "a"}},
+			false},
+	} {
+		failIfNot(t, same(InsertOrdered(tree, test.value).ToSlice(), test.inserted))
+		upserted, found := UpsertOrdered(tree, test.value)
+		failIfNot(t, found == test.found && same(upserted.ToSlice(), test.upserted))
+		unique, err := InsertOrderedUnique(tree, test.value)
+		if test.found {
+			failIfNot(t, errors.Is(err, ErrDuplicateKey) && unique.f == tree.f)
+		} else {
+			failIfNot(t, err == nil && same(unique.ToSlice(), test.inserted))
+		}
+	}
+	empty := taggedTree(&compares)
+	failIfNot(t, same(InsertOrdered(empty, tagged{2, "a"}).ToSlice(), []tagged{{2, "a"}}))
+	unique, err := InsertOrderedUnique(empty, tagged{2, "a"})
+	failIfNot(t, err == nil && unique.Len() == 1)
+	// rejecting duplicates does not need a separate lookup
+	values := make([]tagged, 1000)
+	for i := range values {
+		values[i] = tagged{i * 2, ""}
+	}
+	big := taggedTree(&compares, values...)
+	compares = 0
+	InsertOrdered(big, tagged{501, ""})
+	insertCost := compares
+	compares = 0
+	_, err = InsertOrderedUnique(big, tagged{501, ""})
+	failIfNot(t, err == nil && compares <= insertCost)
+}
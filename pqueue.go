@@ -0,0 +1,185 @@
+package lazyfingertree
+
+import "sync/atomic"
+
+// A PriorityQueue is a persistent max-priority queue. Values with equal priorities
+// come out in the order they were pushed. Entries stay in push order in the tree,
+// which is measured by the best entry and the latest sequence number, so finding the
+// max and finding an entry by handle both take one descent.
+type PriorityQueue[V, P any] struct {
+	tree FingerTree[pqMeasurer[V, P], pqEntry[V, P], pqMeasure[P]]
+}
+
+// A Handle refers to an entry in a PriorityQueue so it can be removed.
+type Handle struct {
+	seq uint64
+}
+
+type pqEntry[V, P any] struct {
+	value    V
+	priority P
+	seq      uint64
+}
+
+type pqMeasure[P any] struct {
+	priority P
+	seq      uint64 // the sequence number of the best entry
+	maxSeq   uint64
+	valid    bool
+}
+
+type pqMeasurer[V, P any] struct {
+	less func(a, b P) bool
+}
+
+// Sequence numbers are unique across all queues so a handle from one branch of a
+// queue never matches an entry pushed onto another branch
+var queueSeqs atomic.Uint64
+
+func (m pqMeasurer[V, P]) Identity() pqMeasure[P] {
+	return pqMeasure[P]{}
+}
+
+func (m pqMeasurer[V, P]) Measure(e pqEntry[V, P]) pqMeasure[P] {
+	return pqMeasure[P]{e.priority, e.seq, e.seq, true}
+}
+
+func (m pqMeasurer[V, P]) Sum(a pqMeasure[P], b pqMeasure[P]) pqMeasure[P] {
+	if !a.valid {
+		return b
+	} else if !b.valid {
+		return a
+	}
+	result := a
+	if m.less(a.priority, b.priority) || (!m.less(b.priority, a.priority) && b.seq < a.seq) {
+		result = b
+	}
+	if b.maxSeq > a.maxSeq {
+		result.maxSeq = b.maxSeq
+	} else {
+		result.maxSeq = a.maxSeq
+	}
+	return result
+}
+
+// Create an empty priority queue that orders priorities with less.
+func NewPriorityQueue[V, P any](less func(a, b P) bool) PriorityQueue[V, P] {
+	return PriorityQueue[V, P]{FromArray(pqMeasurer[V, P]{less}, []pqEntry[V, P]{})}
+}
+
+// Return the number of values in the queue.
+func (q PriorityQueue[V, P]) Len() int {
+	return q.tree.Len()
+}
+
+// Return whether the queue is empty.
+func (q PriorityQueue[V, P]) IsEmpty() bool {
+	return q.tree.IsEmpty()
+}
+
+// Return a queue with value added at priority.
+func (q PriorityQueue[V, P]) Push(value V, priority P) PriorityQueue[V, P] {
+	result, _ := q.PushWithHandle(value, priority)
+	return result
+}
+
+// Return a queue with value added at priority and a handle that can remove it.
+func (q PriorityQueue[V, P]) PushWithHandle(value V, priority P) (PriorityQueue[V, P], Handle) {
+	seq := queueSeqs.Add(1)
+	q.tree = q.tree.AddLast(pqEntry[V, P]{value, priority, seq})
+	return q, Handle{seq}
+}
+
+// Return the value with the highest priority without removing it.
+func (q PriorityQueue[V, P]) PeekMax() (V, P, bool) {
+	if q.tree.IsEmpty() {
+		return null[V](), null[P](), false
+	}
+	loc := q.locateMax()
+	e := loc.value.(pqEntry[V, P])
+	return e.value, e.priority, true
+}
+
+// Remove the value with the highest priority, returning the new queue and the value.
+func (q PriorityQueue[V, P]) PopMax() (PriorityQueue[V, P], V, P, bool) {
+	if q.tree.IsEmpty() {
+		return q, null[V](), null[P](), false
+	}
+	loc := q.locateMax()
+	e := loc.value.(pqEntry[V, P])
+	q.tree = q.removeAt(loc.index)
+	return q, e.value, e.priority, true
+}
+
+func (q PriorityQueue[V, P]) locateMax() location {
+	best := q.tree.Measure().seq
+	return locate(q.tree.f, wrapPredicate(func(m pqMeasure[P]) bool {
+		return m.valid && m.seq == best
+	}), pqMeasure[P]{})
+}
+
+func (q PriorityQueue[V, P]) removeAt(index int) FingerTree[pqMeasurer[V, P], pqEntry[V, P], pqMeasure[P]] {
+	left, right := splitAt(q.tree.f, index)
+	return wrapTree[pqMeasurer[V, P], pqEntry[V, P], pqMeasure[P]](left.Concat(right.RemoveFirst()))
+}
+
+// Remove the entry for a handle, returning false if the entry is not in the queue.
+func (q PriorityQueue[V, P]) RemoveHandle(h Handle) (PriorityQueue[V, P], bool) {
+	index, ok := q.locateHandle(h)
+	if !ok {
+		return q, false
+	}
+	q.tree = q.removeAt(index)
+	return q, true
+}
+
+// Return the index of a handle's entry. Entries are in sequence order, so this takes
+// one descent on the largest sequence number.
+func (q PriorityQueue[V, P]) locateHandle(h Handle) (int, bool) {
+	if h.seq == 0 || q.tree.IsEmpty() || q.tree.Measure().maxSeq < h.seq {
+		return -1, false
+	}
+	loc := locate(q.tree.f, wrapPredicate(func(m pqMeasure[P]) bool {
+		return m.valid && m.maxSeq >= h.seq
+	}), pqMeasure[P]{})
+	if loc.value.(pqEntry[V, P]).seq != h.seq {
+		return -1, false
+	}
+	return loc.index, true
+}
+
+// Return the queue's values in push order.
+func (q PriorityQueue[V, P]) Values() []V {
+	result := make([]V, 0, q.tree.Len())
+	q.tree.Each(func(e pqEntry[V, P]) bool {
+		result = append(result, e.value)
+		return true
+	})
+	return result
+}
+
+// Merge two queues into one. Among values with equal priorities, a's come out before
+// b's, each in their original push order. To keep that order, b's entries are
+// renumbered after a's, which costs O(len(b)) plus O(log n) to join the trees, so
+// pass the smaller queue as b when tie order doesn't matter. Handles from a remain
+// valid in the result. Handles from b must be converted with the returned function,
+// which takes one descent in b and returns other handles unchanged.
+func Meld[V, P any](a, b PriorityQueue[V, P]) (PriorityQueue[V, P], func(Handle) Handle) {
+	count := uint64(b.tree.Len())
+	base := queueSeqs.Add(count) - count
+	builder := newBuilder[pqMeasurer[V, P], pqEntry[V, P], pqMeasure[P]](measurerFor(a.tree.f))
+	seq := base
+	b.tree.Each(func(e pqEntry[V, P]) bool {
+		seq++
+		e.seq = seq
+		builder.Add(e)
+		return true
+	})
+	result := PriorityQueue[V, P]{a.tree.Concat(builder.Tree())}
+	return result, func(h Handle) Handle {
+		if index, ok := b.locateHandle(h); ok {
+			return Handle{base + uint64(index) + 1}
+		}
+		return h
+	}
+}
@@ -0,0 +1,127 @@
+package lazyfingertree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+type pqModelEntry struct {
+	value, priority int
+	seq             int
+	handle          Handle
+}
+
+func TestPriorityQueueHandles(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	q := NewPriorityQueue[int](intLess)
+	model := []pqModelEntry{}
+	removed := []Handle{}
+	for i := 0; i < 2000; i++ {
+		switch op := r.Intn(10); {
+		case op < 5:
+			var h Handle
+			priority := r.Intn(20)
+			q, h = q.PushWithHandle(i, priority)
+			model = append(model, pqModelEntry{i, priority, i, h})
+		case op < 7 && len(model) > 0:
+			sort.SliceStable(model, func(a, b int) bool {
+				if model[a].priority != model[b].priority {
+					return model[a].priority > model[b].priority
+				}
+				return model[a].seq < model[b].seq
+			})
+			var v, p int
+			var ok bool
+			q, v, p, ok = q.PopMax()
+			failIfNot(t, ok && v == model[0].value && p == model[0].priority)
+			removed = append(removed, model[0].handle)
+			model = model[1:]
+		case op < 9 && len(model) > 0:
+			victim := r.Intn(len(model))
+			var ok bool
+			q, ok = q.RemoveHandle(model[victim].handle)
+			failIfNot(t, ok)
+			removed = append(removed, model[victim].handle)
+			model = append(model[:victim], model[victim+1:]...)
+		case len(removed) > 0:
+			var ok bool
+			before := q.Len()
+			q, ok = q.RemoveHandle(removed[r.Intn(len(removed))])
+			failIfNot(t, !ok && q.Len() == before)
+		}
+		failIfNot(t, q.Len() == len(model))
+	}
+	other, h := NewPriorityQueue[int](intLess).PushWithHandle(1, 1)
+	_, ok := q.RemoveHandle(h)
+	failIfNot(t, !ok)
+	_, ok = other.RemoveHandle(h)
+	failIfNot(t, ok)
+}
+
+func TestPriorityQueueBranchHandles(t *testing.T) {
+	q := NewPriorityQueue[string](intLess).Push("base", 1)
+	q1, h1 := q.PushWithHandle("x", 5)
+	q2, h2 := q.PushWithHandle("y", 7)
+	failIfNot(t, h1 != h2)
+	// a handle from a sibling branch does not remove anything
+	after, ok := q2.RemoveHandle(h1)
+	failIfNot(t, !ok && same(after.Values(), []string{"base", "y"}))
+	_, ok = q1.RemoveHandle(h2)
+	failIfNot(t, !ok)
+	after, ok = q1.RemoveHandle(h1)
+	failIfNot(t, ok && same(after.Values(), []string{"base"}))
+}
+
+func TestMeldBranches(t *testing.T) {
+	q := NewPriorityQueue[string](intLess).Push("base", 1)
+	a, ha := q.PushWithHandle("a", 5)
+	b, hb := q.PushWithHandle("b", 5)
+	melded, remap := Meld(a, b)
+	failIfNot(t, same(melded.Values(), []string{"base", "a", "base", "b"}))
+	// b's handle refers to b's entry, not to a's entry from the same root
+	after, ok := melded.RemoveHandle(remap(hb))
+	failIfNot(t, ok && same(after.Values(), []string{"base", "a", "base"}))
+	after, ok = melded.RemoveHandle(remap(ha))
+	failIfNot(t, ok && same(after.Values(), []string{"base", "base", "b"}))
+	_, ok = melded.RemoveHandle(hb)
+	failIfNot(t, !ok)
+}
+
+func TestPriorityQueueFIFO(t *testing.T) {
+	q := NewPriorityQueue[string](intLess)
+	q = q.Push("a", 1).Push("b", 2).Push("c", 1).Push("d", 2)
+	order := []string{}
+	for !q.IsEmpty() {
+		var v string
+		q, v, _, _ = q.PopMax()
+		order = append(order, v)
+	}
+	failIfNot(t, same(order, []string{"b", "d", "a", "c"}))
+	_, _, _, ok := q.PopMax()
+	failIfNot(t, !ok)
+}
+
+func TestMeld(t *testing.T) {
+	a := NewPriorityQueue[string](intLess)
+	b := NewPriorityQueue[string](intLess)
+	a, ha := a.Push("a1", 1).Push("a2", 5).PushWithHandle("a3", 1)
+	b, hb := b.Push("b1", 1).PushWithHandle("b2", 5)
+	b = b.Push("b3", 3)
+	melded, remap := Meld(a, b)
+	failIfNot(t, melded.Len() == 6)
+	melded, ok := melded.RemoveHandle(remap(hb))
+	failIfNot(t, ok)
+	melded, ok = melded.RemoveHandle(remap(ha))
+	failIfNot(t, ok)
+	_, ok = melded.RemoveHandle(hb)
+	failIfNot(t, !ok)
+	melded = melded.Push("new", 1)
+	order := []string{}
+	for !melded.IsEmpty() {
+		var v string
+		melded, v, _, _ = melded.PopMax()
+		order = append(order, v)
+	}
+	failIfNot(t, same(order, []string{"a2", "b3", "a1", "b1", "new"}))
+}
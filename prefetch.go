@@ -0,0 +1,88 @@
+package lazyfingertree
+
+import (
+	"slices"
+	"sync"
+)
+
+// A Prefetcher is a pull iterator that walks its tree in a background goroutine,
+// forcing suspensions ahead of the consumer so that Next rarely waits on evaluation.
+// Forcing is safe for concurrent use, so the tree and trees derived from it can be
+// used while the goroutine runs. Call Close when abandoning iteration early so the
+// goroutine exits.
+type Prefetcher[V any] struct {
+	batches   chan []V
+	done      chan struct{}
+	finished  chan struct{}
+	batch     []V
+	pos       int
+	panicked  any
+	closeOnce sync.Once
+}
+
+const prefetchBatch = 64
+
+// Return a pull iterator that keeps up to about ahead values ready in front of the
+// consumer, see [Prefetcher].
+func (t FingerTree[MS, V, M]) PrefetchIterator(ahead int) *Prefetcher[V] {
+	batchSize := max(1, min(ahead, prefetchBatch))
+	p := &Prefetcher[V]{
+		batches:  make(chan []V, max(1, (ahead+batchSize-1)/batchSize)),
+		done:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	go p.produce(t.tree(), batchSize)
+	return p
+}
+
+func (p *Prefetcher[V]) produce(tree fingerTree, batchSize int) {
+	defer close(p.finished)
+	defer close(p.batches)
+	defer func() {
+		// hand panics from forcing over to the consumer
+		p.panicked = recover()
+	}()
+	c := &chunker[V]{batch: make([]V, 0, batchSize), f: p.send}
+	if c.tree(tree) && len(c.batch) > 0 {
+		p.send(c.batch)
+	}
+}
+
+func (p *Prefetcher[V]) send(batch []V) bool {
+	select {
+	case p.batches <- slices.Clone(batch):
+		return true
+	case <-p.done:
+		return false
+	}
+}
+
+// Return the next value, or false when the values are exhausted or the iterator is closed.
+func (p *Prefetcher[V]) Next() (V, bool) {
+	for p.pos >= len(p.batch) {
+		batch, ok := <-p.batches
+		if !ok {
+			if p.panicked != nil {
+				panicked := p.panicked
+				p.panicked = nil
+				panic(panicked)
+			}
+			return null[V](), false
+		}
+		p.batch, p.pos = batch, 0
+	}
+	p.pos++
+	return p.batch[p.pos-1], true
+}
+
+// Stop the background goroutine and wait for it to exit. Later calls to Next return
+// false. Close can be called more than once.
+func (p *Prefetcher[V]) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		for range p.batches {
+		}
+		<-p.finished
+		p.batch, p.pos, p.panicked = nil, 0, nil
+	})
+}
@@ -0,0 +1,136 @@
+package lazyfingertree
+
+import (
+	"errors"
+	"testing"
+)
+
+// A width measurer that burns time in Sum, like an expensive real measure.
+type slowWidth struct {
+	spin  int
+	panic *bool
+}
+
+func (m slowWidth) Identity() int {
+	return 0
+}
+
+func (m slowWidth) Measure(v int) int {
+	return 1
+}
+
+func (m slowWidth) Sum(a, b int) int {
+	if m.panic != nil && *m.panic {
+		panic(ErrBadValue)
+	}
+	if spin(m.spin) < 0 {
+		panic("negative spin")
+	}
+	return a + b
+}
+
+// Burn time, returning a value so the loop is not optimized away.
+func spin(n int) int {
+	total := 0
+	for i := 0; i < n; i++ {
+		total += i
+	}
+	return total
+}
+
+// Build a lazy tree out of many small concatenated trees.
+func concatenated(meas slowWidth, pieces, size int) FingerTree[slowWidth, int, int] {
+	tree := FromArray(meas, []int{})
+	for p := 0; p < pieces; p++ {
+		nums := make([]int, size)
+		for i := range nums {
+			nums[i] = p*size + i
+		}
+		tree = tree.Concat(FromArray(meas, nums))
+	}
+	return tree
+}
+
+func TestPrefetchIterator(t *testing.T) {
+	tree := concatenated(slowWidth{}, 50, 20)
+	for _, ahead := range []int{0, 1, 10, 1000} {
+		it := tree.PrefetchIterator(ahead)
+		got := []int{}
+		for v, ok := it.Next(); ok; v, ok = it.Next() {
+			got = append(got, v)
+		}
+		it.Close()
+		failIfNot(t, len(got) == 1000)
+		for i, v := range got {
+			failIfNot(t, v == i)
+		}
+	}
+	it := concatenated(slowWidth{}, 50, 20).PrefetchIterator(8)
+	v, ok := it.Next()
+	failIfNot(t, ok && v == 0)
+	it.Close()
+	it.Close()
+	_, ok = it.Next()
+	failIfNot(t, !ok)
+	_, ok = newTree[int]().PrefetchIterator(4).Next()
+	failIfNot(t, !ok)
+}
+
+// Prefetching forces suspensions the tree shares with trees derived from it, so using
+// those trees at the same time must be safe. Run with -race to check.
+func TestPrefetchIteratorShared(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		tree := concatenated(slowWidth{}, 200, 10)
+		it := tree.PrefetchIterator(64)
+		derived := tree.AddFirst(-1).ToSlice()
+		left, right := tree.Split(func(m int) bool { return m > 1000 })
+		failIfNot(t, len(derived) == 2001 && derived[0] == -1 && derived[2000] == 1999)
+		failIfNot(t, left.Len() == 1000 && right.PeekFirst() == 1000 && tree.Get(1500) == 1500)
+		count := 0
+		for v, ok := it.Next(); ok; v, ok = it.Next() {
+			failIfNot(t, v == count)
+			count++
+		}
+		failIfNot(t, count == 2000)
+	}
+}
+
+func TestPrefetchIteratorPanic(t *testing.T) {
+	broken := false
+	tree := concatenated(slowWidth{panic: &broken}, 50, 20)
+	broken = true
+	it := tree.PrefetchIterator(4)
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrBadValue))
+	}()
+	for _, ok := it.Next(); ok; _, ok = it.Next() {
+	}
+	t.Fatal("expected a panic")
+}
+
+func benchmarkIteration(b *testing.B, next func(FingerTree[slowWidth, int, int]) func() (int, bool)) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		tree := concatenated(slowWidth{spin: 2000}, 200, 10)
+		b.StartTimer()
+		nextValue := next(tree)
+		for v, ok := nextValue(); ok; v, ok = nextValue() {
+			if spin(2000) < v {
+				b.Fatal("negative spin")
+			}
+		}
+	}
+}
+
+func BenchmarkIteratorSlowMeasure(b *testing.B) {
+	benchmarkIteration(b, func(tree FingerTree[slowWidth, int, int]) func() (int, bool) {
+		return tree.Iterator()
+	})
+}
+
+func BenchmarkPrefetchSlowMeasure(b *testing.B) {
+	benchmarkIteration(b, func(tree FingerTree[slowWidth, int, int]) func() (int, bool) {
+		return tree.PrefetchIterator(64).Next
+	})
+}
@@ -0,0 +1,357 @@
+package lazyfingertree
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const ropeChunkSize = 1024
+
+// A Rope is an immutable string stored as a finger tree of chunks.
+// Chunks never split a UTF-8 encoded character, so ropes should only be built
+// from and split at character boundaries. Positions can be addressed in bytes,
+// runes, or UTF-16 code units.
+// Lines end with "\n", "\r\n", or a lone "\r".
+type Rope struct {
+	chunks FingerTree[TextMeasurer, string, TextMeasure]
+}
+
+// A TextMeasure measures a string by its length and its line breaks.
+// A "\r\n" pair is one line break, even when it is split across two chunks.
+type TextMeasure struct {
+	Bytes    int
+	Runes    int
+	UTF16    int // the length in UTF-16 code units
+	Lines    int // the number of line breaks
+	startsLF bool
+	endsCR   bool
+}
+
+// TextMeasurer measures strings with TextMeasures.
+type TextMeasurer struct{}
+
+func (m TextMeasurer) Identity() TextMeasure {
+	return TextMeasure{}
+}
+
+func (m TextMeasurer) Measure(value string) TextMeasure {
+	if len(value) == 0 {
+		return TextMeasure{}
+	}
+	runes, utf16 := 0, 0
+	for _, c := range value {
+		runes++
+		utf16++
+		if c >= 0x10000 {
+			utf16++
+		}
+	}
+	// a trailing lone \r counts as a break until Sum sees an \n after it
+	return TextMeasure{
+		Bytes:    len(value),
+		Runes:    runes,
+		UTF16:    utf16,
+		Lines:    strings.Count(value, "\n") + strings.Count(value, "\r") - strings.Count(value, "\r\n"),
+		startsLF: value[0] == '\n',
+		endsCR:   value[len(value)-1] == '\r',
+	}
+}
+
+func (m TextMeasurer) Sum(a TextMeasure, b TextMeasure) TextMeasure {
+	if a.Bytes == 0 {
+		return b
+	} else if b.Bytes == 0 {
+		return a
+	}
+	result := TextMeasure{
+		Bytes:    a.Bytes + b.Bytes,
+		Runes:    a.Runes + b.Runes,
+		UTF16:    a.UTF16 + b.UTF16,
+		Lines:    a.Lines + b.Lines,
+		startsLF: a.startsLF,
+		endsCR:   b.endsCR,
+	}
+	if a.endsCR && b.startsLF {
+		result.Lines--
+	}
+	return result
+}
+
+// Create a rope containing text.
+func NewRope(text string) *Rope {
+	return newRope(text, ropeChunkSize)
+}
+
+func newRope(text string, chunkSize int) *Rope {
+	b := NewBuilder[TextMeasurer](TextMeasurer{})
+	for len(text) > 0 {
+		end := chunkSize
+		if end >= len(text) {
+			end = len(text)
+		} else {
+			for end > 0 && !utf8.RuneStart(text[end]) {
+				end--
+			}
+			if end == 0 {
+				_, end = utf8.DecodeRuneInString(text)
+			}
+		}
+		b.Add(text[:end])
+		text = text[end:]
+	}
+	return &Rope{b.Tree()}
+}
+
+// Return the length of the rope in bytes.
+func (r *Rope) Len() int {
+	return r.chunks.Measure().Bytes
+}
+
+// Return the number of lines in the rope, which is one more than the number of line breaks.
+func (r *Rope) LineCount() int {
+	return r.chunks.Measure().Lines + 1
+}
+
+// Return the rope's chunks.
+func (r *Rope) Chunks() FingerTree[TextMeasurer, string, TextMeasure] {
+	return r.chunks
+}
+
+// Return a rope holding r followed by other.
+func (r *Rope) Concat(other *Rope) *Rope {
+	return &Rope{r.chunks.Concat(other.chunks)}
+}
+
+func (r *Rope) String() string {
+	sb := strings.Builder{}
+	sb.Grow(r.Len())
+	r.chunks.Each(func(chunk string) bool {
+		sb.WriteString(chunk)
+		return true
+	})
+	return sb.String()
+}
+
+// Find the chunk holding offset and the measure of the chunks before it.
+// Returns false if offset is not in the rope.
+func (r *Rope) chunkAt(offset int) (string, TextMeasure, bool) {
+	return r.chunkAtUnit(offset, byteUnits)
+}
+
+// A textUnit is a way of counting positions in a rope.
+type textUnit int
+
+const (
+	byteUnits textUnit = iota
+	runeUnits
+	utf16Units
+)
+
+// Return the length of m in the unit.
+func (u textUnit) of(m TextMeasure) int {
+	switch u {
+	case runeUnits:
+		return m.Runes
+	case utf16Units:
+		return m.UTF16
+	}
+	return m.Bytes
+}
+
+// Find the chunk holding offset, measured in unit, and the measure of the chunks before it.
+func (r *Rope) chunkAtUnit(offset int, unit textUnit) (string, TextMeasure, bool) {
+	if offset < 0 || offset >= unit.of(r.chunks.Measure()) {
+		return "", TextMeasure{}, false
+	}
+	loc := locate(r.chunks.f, wrapPredicate(func(m TextMeasure) bool {
+		return unit.of(m) > offset
+	}), TextMeasure{})
+	return loc.value.(string), loc.before.(TextMeasure), true
+}
+
+// Return the byte index in chunk of the character at offset, which is measured in unit.
+// An offset inside a UTF-16 surrogate pair moves back to the start of its character.
+func byteIndex(chunk string, offset int, unit textUnit) int {
+	if unit == byteUnits {
+		return offset
+	}
+	count := 0
+	for i, c := range chunk {
+		size := 1
+		if c >= 0x10000 && unit == utf16Units {
+			size = 2
+		}
+		if count+size > offset {
+			return i
+		}
+		count += size
+	}
+	return len(chunk)
+}
+
+// Convert an offset in unit to a byte offset, in one descent.
+func (r *Rope) toBytes(offset int, unit textUnit) int {
+	chunk, before, ok := r.chunkAtUnit(offset, unit)
+	if !ok {
+		if offset <= 0 {
+			return 0
+		}
+		return r.Len()
+	}
+	return before.Bytes + byteIndex(chunk, offset-unit.of(before), unit)
+}
+
+// Convert a byte offset to an offset in unit, in one descent.
+func (r *Rope) fromBytes(offset int, unit textUnit) int {
+	chunk, before, ok := r.chunkAt(offset)
+	if !ok {
+		if offset <= 0 {
+			return 0
+		}
+		return unit.of(r.chunks.Measure())
+	}
+	return unit.of(TextMeasurer{}.Sum(before, TextMeasurer{}.Measure(chunk[:offset-before.Bytes])))
+}
+
+// Convert a byte offset to a rune offset.
+func (r *Rope) ByteToRune(offset int) int {
+	return r.fromBytes(offset, runeUnits)
+}
+
+// Convert a rune offset to a byte offset.
+func (r *Rope) RuneToByte(offset int) int {
+	return r.toBytes(offset, runeUnits)
+}
+
+// Convert a byte offset to a UTF-16 offset, as used by LSP.
+func (r *Rope) ByteToUTF16(offset int) int {
+	return r.fromBytes(offset, utf16Units)
+}
+
+// Convert a UTF-16 offset to a byte offset. An offset inside a surrogate pair
+// moves back to the start of its character.
+func (r *Rope) UTF16ToByte(offset int) int {
+	return r.toBytes(offset, utf16Units)
+}
+
+// Split the rope at a byte offset, which should be at a character boundary.
+func (r *Rope) SplitAtByte(offset int) (*Rope, *Rope) {
+	return r.splitAt(offset, byteUnits)
+}
+
+// Split the rope at a rune offset.
+func (r *Rope) SplitAtRune(offset int) (*Rope, *Rope) {
+	return r.splitAt(offset, runeUnits)
+}
+
+func (r *Rope) splitAt(offset int, unit textUnit) (*Rope, *Rope) {
+	left, right := r.chunks.Split(func(m TextMeasure) bool {
+		return unit.of(m) > offset
+	})
+	if !right.IsEmpty() {
+		chunk := right.PeekFirst()
+		if i := byteIndex(chunk, offset-unit.of(left.Measure()), unit); i > 0 {
+			left = left.AddLast(chunk[:i])
+			right = right.RemoveFirst().AddFirst(chunk[i:])
+		}
+	}
+	return &Rope{left}, &Rope{right}
+}
+
+func (r *Rope) byteAt(offset int) (byte, bool) {
+	chunk, before, ok := r.chunkAt(offset)
+	if !ok {
+		return 0, false
+	}
+	return chunk[offset-before.Bytes], true
+}
+
+// Return the byte offset where a line starts, or false if there is no such line.
+func (r *Rope) LineStart(line int) (int, bool) {
+	if line == 0 {
+		return 0, true
+	} else if line < 0 || line >= r.LineCount() {
+		return 0, false
+	}
+	loc := locate(r.chunks.f, wrapPredicate(func(m TextMeasure) bool {
+		return m.Lines >= line
+	}), TextMeasure{})
+	chunk := loc.value.(string)
+	before := loc.before.(TextMeasure)
+	count := before.Lines
+	prevCR := before.endsCR
+	for i := 0; i < len(chunk); i++ {
+		switch chunk[i] {
+		case '\n':
+			if !prevCR {
+				count++
+				if count == line {
+					return before.Bytes + i + 1, true
+				}
+			}
+			prevCR = false
+		case '\r':
+			count++
+			if count == line {
+				// the line starts after the \n if this is a \r\n pair
+				start := before.Bytes + i + 1
+				if c, ok := r.byteAt(start); ok && c == '\n' {
+					start++
+				}
+				return start, true
+			}
+			prevCR = true
+		default:
+			prevCR = false
+		}
+	}
+	panic(fmt.Errorf("%w, line %d not found in the chunk whose measure contains it", ErrFingerTree, line))
+}
+
+// Return the line and byte column for an offset. An offset between the \r and \n of a
+// line break is at the end of its line.
+func (r *Rope) LineOf(offset int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	var prefix TextMeasure
+	if chunk, before, ok := r.chunkAt(offset); ok {
+		prefix = TextMeasurer{}.Sum(before, TextMeasurer{}.Measure(chunk[:offset-before.Bytes]))
+	} else {
+		offset = r.Len()
+		prefix = r.chunks.Measure()
+	}
+	line := prefix.Lines
+	if c, ok := r.byteAt(offset); ok && c == '\n' && prefix.endsCR {
+		line--
+	}
+	start, _ := r.LineStart(line)
+	return line, offset - start
+}
+
+// Build an index of the byte offsets where each line of the rope starts, so
+// Get(lineNo) on the index returns the line's offset in O(log n).
+// Line 0 always starts at 0.
+func BuildLineIndex(r *Rope) FingerTree[SumMeasurer[int], int, int] {
+	starts := []int{0}
+	offset := 0
+	prevCR := false
+	r.chunks.Each(func(chunk string) bool {
+		for i := 0; i < len(chunk); i++ {
+			switch c := chunk[i]; {
+			case c == '\n' && prevCR:
+				// move the start of the line after the \n of the \r\n pair
+				starts[len(starts)-1] = offset + i + 1
+			case c == '\n' || c == '\r':
+				starts = append(starts, offset+i+1)
+			}
+			prevCR = chunk[i] == '\r'
+		}
+		offset += len(chunk)
+		return true
+	})
+	b := NewBuilder[SumMeasurer[int]](SumMeasurer[int]{})
+	b.AddSlice(starts)
+	return b.Tree()
+}
@@ -0,0 +1,110 @@
+package lazyfingertree
+
+import (
+	"strings"
+	"testing"
+)
+
+// the offsets where lines start, scanning text directly
+func lineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\n':
+			starts = append(starts, i+1)
+		case text[i] == '\r' && (i+1 == len(text) || text[i+1] != '\n'):
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func TestLineIndex(t *testing.T) {
+	text := strings.Repeat("one\ntwo two\n\nthree ∑∑∑ three\n", 20) + "last"
+	for _, chunkSize := range []int{1, 3, 7, 1024} {
+		rope := newRope(text, chunkSize)
+		failIfNot(t, rope.String() == text && rope.Len() == len(text))
+		index := BuildLineIndex(rope)
+		expected := lineStarts(text)
+		failIfNot(t, same(index.ToSlice(), expected))
+		for line, offset := range expected {
+			failIfNot(t, index.Get(line) == offset)
+		}
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	for _, text := range []string{
+		"a\r\nb\r\n",
+		"\r\n\r\n\r\r\n\n",
+		"one\rtwo\r\nthree\nfour\r",
+		"\r",
+		"\n\r",
+		"",
+	} {
+		starts := lineStarts(text)
+		// put a chunk boundary at every position, including inside each \r\n
+		for split := 0; split <= len(text); split++ {
+			for _, chunkSize := range []int{1, 2, 1024} {
+				rope := newRope(text[:split], chunkSize).Concat(newRope(text[split:], chunkSize))
+				failIfNot(t, rope.String() == text)
+				failIfErrNow(t, MeasuresConsistent(rope.chunks))
+				if rope.LineCount() != len(starts) {
+					t.Fatalf("%q split at %d: expected %d lines but got %d", text, split, len(starts), rope.LineCount())
+				}
+				failIfNot(t, same(BuildLineIndex(rope).ToSlice(), starts))
+				for line, start := range starts {
+					offset, ok := rope.LineStart(line)
+					failIfNot(t, ok && offset == start)
+				}
+				_, ok := rope.LineStart(len(starts))
+				failIfNot(t, !ok)
+				line := 0
+				for offset := 0; offset <= len(text); offset++ {
+					for line+1 < len(starts) && starts[line+1] <= offset {
+						line++
+					}
+					l, col := rope.LineOf(offset)
+					if l != line || col != offset-starts[line] {
+						t.Fatalf("%q split at %d: offset %d expected %d:%d but got %d:%d", text, split, offset, line, offset-starts[line], l, col)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTextOffsets(t *testing.T) {
+	text := strings.Repeat("aé∑😀\r\n", 9) + "z"
+	runeBytes := []int{}
+	utf16Bytes := []int{}
+	for i, c := range text {
+		runeBytes = append(runeBytes, i)
+		utf16Bytes = append(utf16Bytes, i)
+		if c >= 0x10000 {
+			utf16Bytes = append(utf16Bytes, i)
+		}
+	}
+	runeBytes = append(runeBytes, len(text))
+	utf16Bytes = append(utf16Bytes, len(text))
+	for _, chunkSize := range []int{1, 4, 5, 13, 1024} {
+		rope := newRope(text, chunkSize)
+		m := rope.Chunks().Measure()
+		failIfNot(t, m.Runes == len(runeBytes)-1 && m.UTF16 == len(utf16Bytes)-1)
+		for runeOffset, byteOffset := range runeBytes {
+			failIfNot(t, rope.RuneToByte(runeOffset) == byteOffset)
+			failIfNot(t, rope.ByteToRune(byteOffset) == runeOffset)
+			left, right := rope.SplitAtRune(runeOffset)
+			failIfNot(t, left.String() == text[:byteOffset] && right.String() == text[byteOffset:])
+			left, right = rope.SplitAtByte(byteOffset)
+			failIfNot(t, left.String() == text[:byteOffset] && right.String() == text[byteOffset:])
+			failIfNot(t, left.Concat(right).LineCount() == rope.LineCount())
+		}
+		for utf16Offset, byteOffset := range utf16Bytes {
+			failIfNot(t, rope.UTF16ToByte(utf16Offset) == byteOffset)
+			if utf16Offset == 0 || utf16Bytes[utf16Offset-1] != byteOffset {
+				failIfNot(t, rope.ByteToUTF16(byteOffset) == utf16Offset)
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package lazyfingertree
+
+import (
+	"fmt"
+	"iter"
+)
+
+// A RunSeq is a persistent sequence stored as runs of equal values, for sequences with
+// long runs like highlighting spans. It is measured by its logical length, so logical
+// indexes take one descent. Runs are never empty and adjacent runs never hold equal
+// values; edits split and merge runs to keep it that way.
+type RunSeq[V comparable] struct {
+	tree FingerTree[runMeasurer[V], Run[V], int]
+}
+
+type runMeasurer[V any] struct{}
+
+func (m runMeasurer[V]) Identity() int {
+	return 0
+}
+
+func (m runMeasurer[V]) Measure(run Run[V]) int {
+	return run.Count
+}
+
+func (m runMeasurer[V]) Sum(a int, b int) int {
+	return a + b
+}
+
+// Create an empty run sequence.
+func NewRunSeq[V comparable]() RunSeq[V] {
+	return RunSeq[V]{FromArray(runMeasurer[V]{}, []Run[V]{})}
+}
+
+// Return the logical length of the sequence.
+func (s RunSeq[V]) Len() int {
+	return s.tree.Measure()
+}
+
+// Return the number of runs.
+func (s RunSeq[V]) RunCount() int {
+	return s.tree.Len()
+}
+
+// Return the value at index. This panics if index is out of range.
+func (s RunSeq[V]) Get(index int) V {
+	if index < 0 || index >= s.Len() {
+		panic(fmt.Errorf("%w: %d in sequence of length %d", ErrOutOfRange, index, s.Len()))
+	}
+	loc := locate(s.tree.f, wrapPredicate(func(m int) bool { return m > index }), 0)
+	return loc.value.(Run[V]).Value
+}
+
+// Return a sequence with the values from start up to end set to value.
+// This panics unless 0 <= start <= end <= Len().
+func (s RunSeq[V]) SetRange(start, end int, value V) RunSeq[V] {
+	if start < 0 || start > end || end > s.Len() {
+		panic(fmt.Errorf("%w: range %d-%d in sequence of length %d", ErrOutOfRange, start, end, s.Len()))
+	}
+	left, rest := s.split(start)
+	_, right := RunSeq[V]{rest}.split(end - start)
+	return RunSeq[V]{concatRuns(joinRuns(left, Run[V]{value, end - start}), right)}
+}
+
+// Return a sequence with count copies of value inserted at index.
+// This panics unless 0 <= index <= Len().
+func (s RunSeq[V]) Insert(index int, value V, count int) RunSeq[V] {
+	if index < 0 || index > s.Len() {
+		panic(fmt.Errorf("%w: %d in sequence of length %d", ErrOutOfRange, index, s.Len()))
+	}
+	left, right := s.split(index)
+	return RunSeq[V]{concatRuns(joinRuns(left, Run[V]{value, count}), right)}
+}
+
+// Return the runs in order.
+func (s RunSeq[V]) Runs() iter.Seq[Run[V]] {
+	return func(yield func(Run[V]) bool) {
+		s.tree.Each(IterFunc[Run[V]](yield))
+	}
+}
+
+// Return the values in order, repeating each run's value.
+func (s RunSeq[V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		s.tree.Each(func(run Run[V]) bool {
+			for i := 0; i < run.Count; i++ {
+				if !yield(run.Value) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Split the runs at a logical index, splitting the run that holds it if needed.
+func (s RunSeq[V]) split(index int) (FingerTree[runMeasurer[V], Run[V], int], FingerTree[runMeasurer[V], Run[V], int]) {
+	left, right := s.tree.Split(func(m int) bool { return m > index })
+	if k := index - left.Measure(); k > 0 && !right.IsEmpty() {
+		run := right.PeekFirst()
+		left = left.AddLast(Run[V]{run.Value, k})
+		right = right.RemoveFirst().AddFirst(Run[V]{run.Value, run.Count - k})
+	}
+	return left, right
+}
+
+// Add run to the end of tree, merging it with the last run if they hold equal values.
+func joinRuns[V comparable](tree FingerTree[runMeasurer[V], Run[V], int], run Run[V]) FingerTree[runMeasurer[V], Run[V], int] {
+	if run.Count <= 0 {
+		return tree
+	} else if !tree.IsEmpty() && tree.PeekLast().Value == run.Value {
+		run.Count += tree.PeekLast().Count
+		tree = tree.RemoveLast()
+	}
+	return tree.AddLast(run)
+}
+
+// Join two trees of runs, merging the runs where they meet if they hold equal values.
+func concatRuns[V comparable](left, right FingerTree[runMeasurer[V], Run[V], int]) FingerTree[runMeasurer[V], Run[V], int] {
+	if right.IsEmpty() {
+		return left
+	}
+	return joinRuns(left, right.PeekFirst()).Concat(right.RemoveFirst())
+}
@@ -0,0 +1,71 @@
+package lazyfingertree
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// Check a RunSeq against the values it should hold, including the run invariants.
+func checkRunSeq(t *testing.T, seq RunSeq[byte], want []byte) {
+	t.Helper()
+	failIfNot(t, seq.Len() == len(want))
+	failIfNot(t, slices.Equal(slices.Collect(seq.Values()), want))
+	runs := slices.Collect(seq.Runs())
+	failIfNot(t, seq.RunCount() == len(runs))
+	for i, run := range runs {
+		failIfNot(t, run.Count > 0)
+		failIfNot(t, i == 0 || runs[i-1].Value != run.Value)
+	}
+	for i, v := range want {
+		failIfNot(t, seq.Get(i) == v)
+	}
+}
+
+func TestRunSeqBoundaries(t *testing.T) {
+	seq := NewRunSeq[byte]().Insert(0, 'a', 3).Insert(3, 'b', 2).Insert(5, 'a', 1)
+	checkRunSeq(t, seq, []byte("aaabba"))
+	// merge with both neighbors
+	checkRunSeq(t, seq.SetRange(3, 5, 'a'), []byte("aaaaaa"))
+	failIfNot(t, seq.SetRange(3, 5, 'a').RunCount() == 1)
+	// split a run in the middle
+	checkRunSeq(t, seq.SetRange(1, 2, 'c'), []byte("acabba"))
+	// exactly one run, at the start, and at the end
+	checkRunSeq(t, seq.SetRange(3, 5, 'c'), []byte("aaacca"))
+	checkRunSeq(t, seq.SetRange(0, 1, 'b'), []byte("baabba"))
+	checkRunSeq(t, seq.SetRange(5, 6, 'b'), []byte("aaabbb"))
+	// empty ranges and inserts at the ends
+	checkRunSeq(t, seq.SetRange(2, 2, 'z'), []byte("aaabba"))
+	checkRunSeq(t, seq.Insert(0, 'a', 2), []byte("aaaaabba"))
+	checkRunSeq(t, seq.Insert(6, 'a', 2), []byte("aaabbaaa"))
+	checkRunSeq(t, seq.Insert(4, 'b', 1), []byte("aaabbba"))
+	checkRunSeq(t, seq.Insert(2, 'a', 0), []byte("aaabba"))
+	defer func() {
+		err, _ := recover().(error)
+		failIfNot(t, errors.Is(err, ErrOutOfRange))
+	}()
+	seq.Get(6)
+}
+
+func TestRunSeqRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	seq := NewRunSeq[byte]()
+	want := []byte{}
+	for i := 0; i < 500; i++ {
+		v := byte('a' + rng.Intn(3))
+		if rng.Intn(3) == 0 || len(want) == 0 {
+			pos, n := rng.Intn(len(want)+1), rng.Intn(5)
+			seq = seq.Insert(pos, v, n)
+			want = slices.Insert(want, pos, slices.Repeat([]byte{v}, n)...)
+		} else {
+			start := rng.Intn(len(want) + 1)
+			end := start + rng.Intn(len(want)-start+1)
+			seq = seq.SetRange(start, end, v)
+			for j := start; j < end; j++ {
+				want[j] = v
+			}
+		}
+		checkRunSeq(t, seq, want)
+	}
+}
@@ -0,0 +1,147 @@
+package lazyfingertree
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Pick a value at random with probability proportional to its weight, where weightOf
+// turns a sum-of-weights measure into a total weight. This is one split at a random
+// threshold. Values with zero weight are never picked. This returns false if the
+// total weight is not positive.
+func PickWeighted[MS Measurer[V, M], V, M any](t FingerTree[MS, V, M], r *rand.Rand, weightOf func(M) float64) (V, bool) {
+	_, v, _, ok := pickWeighted(t, r, weightOf)
+	return v, ok
+}
+
+// Pick up to n values at random without replacement, each pick weighted like
+// [PickWeighted] among the values that have not been picked yet. This returns fewer
+// than n values if it runs out of values with positive weight.
+func PickN[MS Measurer[V, M], V, M any](t FingerTree[MS, V, M], r *rand.Rand, n int, weightOf func(M) float64) []V {
+	var picks []V
+	for len(picks) < n {
+		left, v, right, ok := pickWeighted(t, r, weightOf)
+		if !ok {
+			break
+		}
+		picks = append(picks, v)
+		t = left.Concat(right)
+	}
+	return picks
+}
+
+// Split t around a value picked by weight, returning the values before and after it.
+func pickWeighted[MS Measurer[V, M], V, M any](t FingerTree[MS, V, M], r *rand.Rand, weightOf func(M) float64) (FingerTree[MS, V, M], V, FingerTree[MS, V, M], bool) {
+	total := weightOf(t.Measure())
+	if !(total > 0) {
+		return t, null[V](), t, false
+	}
+	threshold := r.Float64() * total
+	left, right := t.Split(func(m M) bool {
+		return weightOf(m) > threshold
+	})
+	if right.IsEmpty() {
+		// rounding put the threshold at or past the last prefix sum, so take the last
+		// value with positive weight
+		meas := t.measurer()
+		for !left.IsEmpty() && !(weightOf(meas.Measure(left.PeekLast())) > 0) {
+			right = right.AddFirst(left.PeekLast())
+			left = left.RemoveLast()
+		}
+		if left.IsEmpty() {
+			return t, null[V](), t, false
+		}
+		right = right.AddFirst(left.PeekLast())
+		left = left.RemoveLast()
+	}
+	return left, right.PeekFirst(), right.RemoveFirst(), true
+}
+
+// Return n values chosen uniformly at random without replacement, in random order.
+// If n is at least Len this returns all of the values shuffled. For small samples
+// this picks n distinct indexes and fetches them all in one walk down the tree
+// instead of converting the whole tree to a slice.
+func (t FingerTree[MS, V, M]) Sample(n int, r *rand.Rand) []V {
+	size := t.Len()
+	if n <= 0 {
+		return nil
+	} else if n > size {
+		n = size
+	}
+	var result []V
+	if n*4 < size {
+		// Floyd's algorithm picks n distinct indexes in O(n)
+		chosen := make(map[int]bool, n)
+		indexes := make([]int, 0, n)
+		for j := size - n; j < size; j++ {
+			i := r.Intn(j + 1)
+			if chosen[i] {
+				i = j
+			}
+			chosen[i] = true
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		w := &indexWalker{indexes: indexes}
+		w.walkTree(t.tree(), 0)
+		result = make([]V, n)
+		for i, v := range w.values {
+			result[i] = v.(V)
+		}
+	} else {
+		result = t.ToSlice()
+		for i := 0; i < n; i++ {
+			j := i + r.Intn(len(result)-i)
+			result[i], result[j] = result[j], result[i]
+		}
+		result = result[:n]
+	}
+	r.Shuffle(n, func(i, j int) {
+		result[i], result[j] = result[j], result[i]
+	})
+	return result
+}
+
+// An indexWalker fetches the values at sorted indexes in one walk, only descending
+// into the parts of the tree that hold the next index.
+type indexWalker struct {
+	indexes []int
+	values  []any
+}
+
+// Return whether the next index is before end.
+func (w *indexWalker) wants(end int) bool {
+	return len(w.values) < len(w.indexes) && w.indexes[len(w.values)] < end
+}
+
+func (w *indexWalker) walkTree(tree fingerTree, offset int) {
+	if !w.wants(offset + tree.size()) {
+		return
+	}
+	switch tr := force(tree).(type) {
+	case *singleTree:
+		w.walkItem(tr.value, offset)
+	case *deepTree:
+		offset = w.walkItems(tr.left.items, offset)
+		w.walkTree(tr.mid, offset)
+		w.walkItems(tr.right.items, offset+tr.mid.size())
+	}
+}
+
+func (w *indexWalker) walkItems(items []any, offset int) int {
+	for _, item := range items {
+		w.walkItem(item, offset)
+		offset += sizeOf(item)
+	}
+	return offset
+}
+
+func (w *indexWalker) walkItem(item any, offset int) {
+	if !w.wants(offset + sizeOf(item)) {
+		return
+	} else if n, ok := item.(*node); ok {
+		w.walkItems(n.children, offset)
+	} else {
+		w.values = append(w.values, item)
+	}
+}
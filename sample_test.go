@@ -0,0 +1,106 @@
+package lazyfingertree
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func weight(m float64) float64 {
+	return m
+}
+
+func TestPickWeighted(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	weights := []float64{0, 1, 0, 2, 0.5, 0, 4.5, 0}
+	tree := FromArray(SumMeasurer[float64]{}, weights)
+	counts := map[float64]int{}
+	const draws = 200000
+	for i := 0; i < draws; i++ {
+		v, ok := PickWeighted(tree, rng, weight)
+		failIfNot(t, ok)
+		counts[v]++
+	}
+	failIfNot(t, counts[0] == 0)
+	for _, w := range []float64{1, 2, 0.5, 4.5} {
+		freq := float64(counts[w]) / draws
+		failIfNot(t, math.Abs(freq-w/8) < 0.01)
+	}
+	_, ok := PickWeighted(FromArray(SumMeasurer[float64]{}, []float64{0, 0}), rng, weight)
+	failIfNot(t, !ok)
+	_, ok = PickWeighted(FromArray(SumMeasurer[float64]{}, []float64{}), rng, weight)
+	failIfNot(t, !ok)
+}
+
+// A source whose Float64 is as close to 1 as possible, putting thresholds at the far edge.
+type edgeSource struct{}
+
+func (edgeSource) Int63() int64 {
+	return 1<<63 - 1025
+}
+
+func (edgeSource) Seed(int64) {}
+
+func TestPickWeightedEdges(t *testing.T) {
+	rng := rand.New(edgeSource{})
+	tree := FromArray(SumMeasurer[float64]{}, []float64{0.1, 0.2, 0.3, 0, 0})
+	v, ok := PickWeighted(tree, rng, weight)
+	failIfNot(t, ok && v == 0.3)
+	single := FromArray(SumMeasurer[float64]{}, []float64{0, 3, 0})
+	for i := 0; i < 100; i++ {
+		v, ok = PickWeighted(single, rand.New(rand.NewSource(int64(i))), weight)
+		failIfNot(t, ok && v == 3)
+	}
+}
+
+func TestPickN(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	tree := FromArray(SumMeasurer[float64]{}, []float64{1, 0, 2, 3, 0, 4})
+	for i := 0; i < 100; i++ {
+		picks := PickN(tree, rng, 10, weight)
+		slices.Sort(picks)
+		failIfNot(t, slices.Equal(picks, []float64{1, 2, 3, 4}))
+	}
+	failIfNot(t, len(PickN(tree, rng, 2, weight)) == 2)
+	// heavier values tend to be picked first
+	first := map[float64]int{}
+	for i := 0; i < 10000; i++ {
+		first[PickN(tree, rng, 2, weight)[0]]++
+	}
+	failIfNot(t, first[4] > first[3] && first[3] > first[2] && first[2] > first[1])
+}
+
+func TestSample(t *testing.T) {
+	rng := rand.New(rand.NewSource(21))
+	nums := make([]int, 1000)
+	for i := range nums {
+		nums[i] = i
+	}
+	tree := newTree(nums...)
+	counts := make([]int, len(nums))
+	for _, n := range []int{1, 10, 100, 249, 250, 900} {
+		for trial := 0; trial < 200; trial++ {
+			sample := tree.Sample(n, rng)
+			failIfNot(t, len(sample) == n)
+			seen := map[int]bool{}
+			for _, v := range sample {
+				failIfNot(t, !seen[v] && v >= 0 && v < len(nums))
+				seen[v] = true
+				if n == 10 {
+					counts[v]++
+				}
+			}
+		}
+	}
+	// 2000 picks over 1000 values, so no value should be picked very often
+	failIfNot(t, slices.Max(counts) < 12)
+	all := tree.Sample(5000, rng)
+	failIfNot(t, !slices.Equal(all, nums))
+	slices.Sort(all)
+	failIfNot(t, slices.Equal(all, nums))
+	failIfNot(t, tree.Sample(0, rng) == nil && len(newTree[int]().Sample(3, rng)) == 0)
+	a := tree.Sample(20, rand.New(rand.NewSource(1)))
+	b := tree.Sample(20, rand.New(rand.NewSource(1)))
+	failIfNot(t, slices.Equal(a, b))
+}
@@ -0,0 +1,476 @@
+package lazyfingertree
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// Find the value at which the prefix measure first reaches target (is not less than it).
+// If the prefix measure ending with that value is eq to target, return the value,
+// its index, and true. Otherwise return false with an index of -1.
+// In a tree sorted by a max-key measure this is an exact key lookup.
+func (t FingerTree[MS, V, M]) FindByMeasure(target M, eq func(M, M) bool, less func(M, M) bool) (V, int, bool) {
+	pred := wrapPredicate(func(m M) bool { return !less(m, target) })
+	if isEmpty(t.tree()) || !pred(t.tree().measurement().value) {
+		return null[V](), -1, false
+	}
+	meas := measurerFor(t.tree())
+	left, mid, _ := t.tree().splitTree(pred, meas.Identity())
+	boundary := meas.Sum(left.measurement().value, meas.Measure(mid))
+	if !eq(boundary.(M), target) {
+		return null[V](), -1, false
+	}
+	return mid.(V), left.size(), true
+}
+
+// Return how many values have keys from lo up to hi in a tree sorted by a max-key
+// measure, where less compares measures by key. This takes two descents, one for
+// the rank of each bound.
+func (t FingerTree[MS, V, M]) CountInKeyRange(lo, hi M, less func(M, M) bool) int {
+	if !less(lo, hi) {
+		return 0
+	}
+	return t.keyRank(hi, less) - t.keyRank(lo, less)
+}
+
+// Return the number of values with keys less than key, without building any trees.
+func (t FingerTree[MS, V, M]) keyRank(key M, less func(M, M) bool) int {
+	pred := wrapPredicate(func(m M) bool { return !less(m, key) })
+	if isEmpty(t.tree()) || !pred(t.tree().measurement().value) {
+		return t.tree().size()
+	}
+	return locate(t.tree(), pred, measurerFor(t.tree()).Identity()).index
+}
+
+// Find the value whose starting boundary (the measure of all the values before it)
+// is nearest to target according to dist. This considers the values on both sides
+// of the point where the prefix measure passes target, preferring the earlier one on
+// ties. Targets before the start or past the end of the tree clamp to the first or
+// last value. Returns false if the tree is empty.
+func (t FingerTree[MS, V, M]) NearestByMeasure(target M, dist func(a, b M) float64, cmp func(M, M) int) (int, V, bool) {
+	if isEmpty(t.tree()) {
+		return -1, null[V](), false
+	}
+	pred := wrapPredicate(func(m M) bool { return cmp(m, target) > 0 })
+	if !pred(t.tree().measurement().value) {
+		return t.tree().size() - 1, t.tree().PeekLast().(V), true
+	}
+	meas := measurerFor(t.tree())
+	left, mid, right := t.tree().splitTree(pred, meas.Identity())
+	start := left.measurement().value
+	end := meas.Sum(start, meas.Measure(mid))
+	if !isEmpty(right) && dist(end.(M), target) < dist(start.(M), target) {
+		return left.size() + 1, right.PeekFirst().(V), true
+	}
+	return left.size(), mid.(V), true
+}
+
+var ErrUnsorted = fmt.Errorf("%w, values are not sorted", ErrFingerTree)
+
+// Return the value at each quantile in fracs, where weight converts measures into
+// numbers. The value at quantile f is the first one where the cumulative weight reaches
+// f times the total weight. Fracs must be sorted in ascending order, which lets each
+// descent start where the previous one ended. Returns nil if the tree is empty.
+func (t FingerTree[MS, V, M]) Quantiles(fracs []float64, weight func(M) float64) []V {
+	if isEmpty(t.tree()) {
+		return nil
+	}
+	meas := measurerFor(t.tree())
+	total := weight(t.Measure())
+	result := make([]V, len(fracs))
+	rest := t.tree()
+	acc := meas.Identity()
+	for i, frac := range fracs {
+		if i > 0 && frac < fracs[i-1] {
+			panic(fmt.Errorf("%w: fraction %v follows %v", ErrUnsorted, frac, fracs[i-1]))
+		}
+		threshold := frac * total
+		pred := wrapPredicate(func(m M) bool { return weight(m) >= threshold })
+		if !pred(meas.Sum(acc, rest.measurement().value)) {
+			result[i] = t.tree().PeekLast().(V)
+			continue
+		}
+		left, mid, right := rest.splitTree(pred, acc)
+		result[i] = mid.(V)
+		acc = meas.Sum(acc, left.measurement().value)
+		rest = right.AddFirst(mid)
+	}
+	return result
+}
+
+// Return whether sub occurs as a contiguous run of values in the tree, comparing
+// values with eq. This scans the tree once without materializing it, using KMP so it
+// takes linear time. An empty sub is always contained.
+func (t FingerTree[MS, V, M]) ContainsSubsequence(sub []V, eq func(V, V) bool) bool {
+	if len(sub) == 0 {
+		return true
+	}
+	// fail[i] is the length of the longest proper prefix of sub[:i+1] that is also its suffix
+	fail := make([]int, len(sub))
+	for i, k := 1, 0; i < len(sub); i++ {
+		for k > 0 && !eq(sub[i], sub[k]) {
+			k = fail[k-1]
+		}
+		if eq(sub[i], sub[k]) {
+			k++
+		}
+		fail[i] = k
+	}
+	matched := 0
+	return !t.tree().Each(wrapIter(func(v V) bool {
+		for matched > 0 && !eq(v, sub[matched]) {
+			matched = fail[matched-1]
+		}
+		if eq(v, sub[matched]) {
+			matched++
+		}
+		return matched < len(sub)
+	}))
+}
+
+// Return the first and last indexes where pred is true of the value, in one traversal.
+// This returns false if pred is never true.
+func (t FingerTree[MS, V, M]) MatchRange(pred func(V) bool) (first, last int, ok bool) {
+	index := 0
+	t.Each(func(v V) bool {
+		if pred(v) {
+			if !ok {
+				first, ok = index, true
+			}
+			last = index
+		}
+		index++
+		return true
+	})
+	return first, last, ok
+}
+
+// Return the indexes of the values where pred is true, in ascending order, in one traversal.
+func (t FingerTree[MS, V, M]) Indices(pred func(V) bool) []int {
+	var indexes []int
+	index := 0
+	t.Each(func(v V) bool {
+		if pred(v) {
+			indexes = append(indexes, index)
+		}
+		index++
+		return true
+	})
+	return indexes
+}
+
+// Return the indexes i where eq(v[i], v[i-1]) holds, in ascending order, in one
+// traversal. These are the values that removing consecutive duplicates would drop.
+func (t FingerTree[MS, V, M]) AdjacentDuplicates(eq func(V, V) bool) []int {
+	var indexes []int
+	var prev V
+	index := 0
+	t.Each(func(v V) bool {
+		if index > 0 && eq(v, prev) {
+			indexes = append(indexes, index)
+		}
+		prev = v
+		index++
+		return true
+	})
+	return indexes
+}
+
+// Return the start and length of the longest run of consecutive values where pred is
+// true, in one traversal. The first of equally long runs wins and the length is 0 if
+// pred is never true.
+func (t FingerTree[MS, V, M]) LongestRun(pred func(V) bool) (start, length int) {
+	index, runStart := 0, 0
+	t.Each(func(v V) bool {
+		if !pred(v) {
+			runStart = index + 1
+		} else if index+1-runStart > length {
+			start, length = runStart, index+1-runStart
+		}
+		index++
+		return true
+	})
+	return start, length
+}
+
+// Return the last value where pred is true, scanning from the back so values before
+// it are not visited and the tree's length is not needed. This returns false if pred
+// is never true.
+func (t FingerTree[MS, V, M]) FindLast(pred func(V) bool) (V, bool) {
+	var value V
+	found := false
+	t.EachReverse(func(v V) bool {
+		if pred(v) {
+			value, found = v, true
+			return false
+		}
+		return true
+	})
+	return value, found
+}
+
+// Return the index of the last value where pred is true, scanning from the back so
+// values before it are not visited. The tree's length is only taken once a match is
+// found, to turn the match's distance from the back into an index. This returns -1 if
+// pred is never true.
+func (t FingerTree[MS, V, M]) LastIndexFunc(pred func(V) bool) int {
+	fromBack := 0
+	found := false
+	t.EachReverse(func(v V) bool {
+		if pred(v) {
+			found = true
+			return false
+		}
+		fromBack++
+		return true
+	})
+	if !found {
+		return -1
+	}
+	return t.tree().size() - 1 - fromBack
+}
+
+// Return the k largest values according to less, largest first, in one traversal that
+// keeps at most k values in a heap, so it takes O(n log k). Equal values are in tree
+// order. Less need not agree with the tree's measure. This returns nil if k <= 0.
+func TopK[MS Measurer[V, M], V, M any](t FingerTree[MS, V, M], k int, less func(V, V) bool) []V {
+	if k <= 0 || isEmpty(t.tree()) {
+		return nil
+	}
+	best := &topHeap[V]{less: less}
+	t.Each(func(v V) bool {
+		if len(best.items) < k {
+			heap.Push(best, v)
+		} else if less(best.items[0], v) {
+			// an equal value comes after the ones already kept so it never replaces them
+			best.seqs[0] = best.next
+			best.items[0] = v
+			best.next++
+			heap.Fix(best, 0)
+		}
+		return true
+	})
+	result := make([]V, len(best.items))
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(best).(V)
+	}
+	return result
+}
+
+// A min-heap of the best values seen so far: the top is the smallest value, with the
+// latest one breaking ties. The sequence numbers record tree order.
+type topHeap[V any] struct {
+	items []V
+	seqs  []int
+	next  int
+	less  func(V, V) bool
+}
+
+func (h *topHeap[V]) Len() int {
+	return len(h.items)
+}
+
+func (h *topHeap[V]) Less(i, j int) bool {
+	if h.less(h.items[i], h.items[j]) {
+		return true
+	} else if h.less(h.items[j], h.items[i]) {
+		return false
+	}
+	return h.seqs[i] > h.seqs[j]
+}
+
+func (h *topHeap[V]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.seqs[i], h.seqs[j] = h.seqs[j], h.seqs[i]
+}
+
+func (h *topHeap[V]) Push(x any) {
+	h.items = append(h.items, x.(V))
+	h.seqs = append(h.seqs, h.next)
+	h.next++
+}
+
+func (h *topHeap[V]) Pop() any {
+	last := len(h.items) - 1
+	result := h.items[last]
+	h.items = h.items[:last]
+	h.seqs = h.seqs[:last]
+	return result
+}
+
+// The result of locating a value without splitting the tree
+type location struct {
+	value   any
+	prev    any // the item before value, which may be a node
+	hasPrev bool
+	before  any // the measure of everything before value
+	index   int
+}
+
+// Find the first value where pred is true for the prefix measure ending with it,
+// without building any trees. The predicate must be true for the whole tree.
+func locate(tree fingerTree, pred predicate, acc any) location {
+	loc := location{before: acc}
+	locateTree(tree, pred, &loc)
+	if loc.hasPrev {
+		loc.prev = lastLeaf(loc.prev)
+	}
+	return loc
+}
+
+func locateTree(tree fingerTree, pred predicate, loc *location) {
+	switch t := force(tree).(type) {
+	case *singleTree:
+		locateItem(t.value, pred, loc)
+	case *deepTree:
+		meas := t.measurer
+		leftMeasure := meas.Sum(loc.before, t.left._measurement.value)
+		if pred(leftMeasure) {
+			locateIn(meas, t.left.items, pred, loc)
+			return
+		}
+		loc.prev, loc.hasPrev = t.left.peekLast(), true
+		midMeasure := meas.Sum(leftMeasure, t.mid.measurement().value)
+		if pred(midMeasure) {
+			loc.before = leftMeasure
+			loc.index += t.left._size
+			locateTree(t.mid, pred, loc)
+			return
+		}
+		if !isEmpty(t.mid) {
+			loc.prev = t.mid.PeekLast()
+		}
+		loc.before = midMeasure
+		loc.index += t.left._size + t.mid.size()
+		locateIn(meas, t.right.items, pred, loc)
+	}
+}
+
+func locateIn(meas measurer, items []any, pred predicate, loc *location) {
+	for i, item := range items {
+		next := meas.Sum(loc.before, meas.Measure(item))
+		if pred(next) || i == len(items)-1 {
+			locateItem(item, pred, loc)
+			return
+		}
+		loc.prev, loc.hasPrev = item, true
+		loc.before = next
+		loc.index += sizeOf(item)
+	}
+}
+
+func locateItem(item any, pred predicate, loc *location) {
+	if n, ok := item.(*node); ok {
+		locateIn(n._measurement.measurer, n.children, pred, loc)
+		return
+	}
+	loc.value = item
+}
+
+// Find the last value where pred is true for the suffix measure starting with it,
+// summing from the right without building any trees, and return its index. The
+// predicate must be true for the whole tree.
+func locateSuffix(tree fingerTree, pred predicate) int {
+	loc := suffixLocation{after: measurerFor(tree).Identity()}
+	locateSuffixTree(tree, pred, &loc)
+	return tree.size() - loc.count - 1
+}
+
+// The progress of locateSuffix: the measure and number of values after the current position
+type suffixLocation struct {
+	after any
+	count int
+}
+
+func locateSuffixTree(tree fingerTree, pred predicate, loc *suffixLocation) {
+	switch t := force(tree).(type) {
+	case *singleTree:
+		locateSuffixItem(t.value, pred, loc)
+	case *deepTree:
+		meas := t.measurer
+		rightMeasure := meas.Sum(t.right._measurement.value, loc.after)
+		if pred(rightMeasure) {
+			locateSuffixIn(meas, t.right.items, pred, loc)
+			return
+		}
+		midMeasure := meas.Sum(t.mid.measurement().value, rightMeasure)
+		if pred(midMeasure) {
+			loc.after = rightMeasure
+			loc.count += t.right._size
+			locateSuffixTree(t.mid, pred, loc)
+			return
+		}
+		loc.after = midMeasure
+		loc.count += t.right._size + t.mid.size()
+		locateSuffixIn(meas, t.left.items, pred, loc)
+	}
+}
+
+func locateSuffixIn(meas measurer, items []any, pred predicate, loc *suffixLocation) {
+	for i := len(items) - 1; i >= 0; i-- {
+		next := meas.Sum(meas.Measure(items[i]), loc.after)
+		if pred(next) || i == 0 {
+			locateSuffixItem(items[i], pred, loc)
+			return
+		}
+		loc.after = next
+		loc.count += sizeOf(items[i])
+	}
+}
+
+func locateSuffixItem(item any, pred predicate, loc *suffixLocation) {
+	if n, ok := item.(*node); ok {
+		locateSuffixIn(n._measurement.measurer, n.children, pred, loc)
+	}
+}
+
+// Return the value at index without building any trees. Index must be in range.
+func valueAt(tree fingerTree, index int) any {
+	for {
+		switch t := force(tree).(type) {
+		case *singleTree:
+			return itemAt(t.value, index)
+		case *deepTree:
+			if index < t.left._size {
+				return itemsAt(t.left.items, index)
+			}
+			index -= t.left._size
+			if midSize := t.mid.size(); index < midSize {
+				tree = t.mid
+				continue
+			} else {
+				index -= midSize
+			}
+			return itemsAt(t.right.items, index)
+		}
+		panic(fmt.Errorf("%w: %d", ErrOutOfRange, index))
+	}
+}
+
+func itemsAt(items []any, index int) any {
+	for _, item := range items {
+		if size := sizeOf(item); index < size {
+			return itemAt(item, index)
+		} else {
+			index -= size
+		}
+	}
+	panic(fmt.Errorf("%w: %d", ErrOutOfRange, index))
+}
+
+func itemAt(item any, index int) any {
+	if n, ok := item.(*node); ok {
+		return itemsAt(n.children, index)
+	}
+	return item
+}
+
+func lastLeaf(item any) any {
+	for {
+		n, ok := item.(*node)
+		if !ok {
+			return item
+		}
+		item = n.children[len(n.children)-1]
+	}
+}
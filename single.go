@@ -19,8 +19,17 @@ func (s *singleTree) measurement() measurement {
 	return s._measurement
 }
 
+func (s *singleTree) size() int {
+	return sizeOf(s.value)
+}
+
 type nodeMeasurer struct {
 	measurer measurer
+	strict   bool
+}
+
+func newNodeMeasurer(m measurer) nodeMeasurer {
+	return nodeMeasurer{m, isStrict(m)}
 }
 
 func (m nodeMeasurer) Identity() any {
@@ -36,7 +45,7 @@ func (m nodeMeasurer) Sum(a any, b any) any {
 }
 
 func makeEmptyMid(m measurer) fingerTree {
-	return newEmptyTree(nodeMeasurer{m})
+	return newEmptyTree(newNodeMeasurer(m))
 }
 
 func (s *singleTree) String() string {
@@ -90,6 +99,10 @@ func (s *singleTree) splitTree(predicate predicate, initial any) (fingerTree, an
 	return s._measurement.empty(), s.value, s._measurement.empty()
 }
 
+func (s *singleTree) splitIndex(index int) (fingerTree, any, fingerTree) {
+	return s._measurement.empty(), s.value, s._measurement.empty()
+}
+
 func (s *singleTree) Split(predicate predicate) (fingerTree, fingerTree) {
 	if predicate(s._measurement.value) {
 		return s._measurement.empty(), s
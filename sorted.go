@@ -0,0 +1,174 @@
+package lazyfingertree
+
+import "sort"
+
+// Return the first index i where less(v[i], v[i-1]), i.e. where the values stop being
+// non-decreasing. Returns false if the tree is sorted.
+func (t FingerTree[MS, V, M]) FirstDescent(less func(V, V) bool) (int, bool) {
+	index := -1
+	found := false
+	var prev V
+	t.Each(func(v V) bool {
+		index++
+		if index > 0 && less(v, prev) {
+			found = true
+			return false
+		}
+		prev = v
+		return true
+	})
+	if !found {
+		return -1, false
+	}
+	return index, true
+}
+
+// Return the number of values two sorted trees have in common, walking both trees
+// together without materializing them. Repeated values are matched one for one.
+func OverlapCount[MS Measurer[V, M], V, M any](a, b FingerTree[MS, V, M], less func(V, V) bool) int {
+	ca, cb := newCursor(a.tree()), newCursor(b.tree())
+	va, okA := ca.next()
+	vb, okB := cb.next()
+	count := 0
+	for okA && okB {
+		if less(va.(V), vb.(V)) {
+			va, okA = ca.next()
+		} else if less(vb.(V), va.(V)) {
+			vb, okB = cb.next()
+		} else {
+			count++
+			va, okA = ca.next()
+			vb, okB = cb.next()
+		}
+	}
+	return count
+}
+
+// Merge two sorted trees into a sorted tree. The merge is stable: values from a come
+// before equal values from b.
+func Merge[MS Measurer[V, M], V, M any](a, b FingerTree[MS, V, M], less func(V, V) bool) FingerTree[MS, V, M] {
+	builder := newBuilder[MS, V, M](measurerFor(a.tree()))
+	ca, cb := newCursor(a.tree()), newCursor(b.tree())
+	va, okA := ca.next()
+	vb, okB := cb.next()
+	for okA && okB {
+		if less(vb.(V), va.(V)) {
+			builder.Add(vb.(V))
+			vb, okB = cb.next()
+		} else {
+			builder.Add(va.(V))
+			va, okA = ca.next()
+		}
+	}
+	for ; okA; va, okA = ca.next() {
+		builder.Add(va.(V))
+	}
+	for ; okB; vb, okB = cb.next() {
+		builder.Add(vb.(V))
+	}
+	return builder.Tree()
+}
+
+// Join two sorted trees into a sorted tree. If every value in other is at least as large
+// as every value in t, this is just Concat and returns true, otherwise it falls back to
+// Merge and returns false.
+func (t FingerTree[MS, V, M]) ConcatSorted(other FingerTree[MS, V, M], less func(V, V) bool) (FingerTree[MS, V, M], bool) {
+	if t.IsEmpty() || other.IsEmpty() || !less(other.PeekFirst(), t.PeekLast()) {
+		return t.Concat(other), true
+	}
+	return Merge(t, other, less), false
+}
+
+// Split the tree into its longest non-decreasing prefix and the rest.
+func (t FingerTree[MS, V, M]) SortedPrefix(less func(V, V) bool) (FingerTree[MS, V, M], FingerTree[MS, V, M]) {
+	index, found := t.FirstDescent(less)
+	if !found {
+		return t, wrapTree[MS, V, M](empty(t.tree()))
+	}
+	left, right := splitAt(t.tree(), index)
+	return wrapTree[MS, V, M](left), wrapTree[MS, V, M](right)
+}
+
+// Split the tree into its maximal non-decreasing runs, for natural merge sort.
+// This finds the run boundaries in one pass and splits the tree at each of them,
+// so the runs share structure with the tree.
+func (t FingerTree[MS, V, M]) AscendingRuns(less func(V, V) bool) []FingerTree[MS, V, M] {
+	if t.IsEmpty() {
+		return nil
+	}
+	var starts []int
+	index := -1
+	var prev V
+	t.Each(func(v V) bool {
+		index++
+		if index > 0 && less(v, prev) {
+			starts = append(starts, index)
+		}
+		prev = v
+		return true
+	})
+	runs := make([]FingerTree[MS, V, M], 0, len(starts)+1)
+	rest := t.tree()
+	offset := 0
+	for _, start := range starts {
+		var run fingerTree
+		run, rest = splitAt(rest, start-offset)
+		runs = append(runs, wrapTree[MS, V, M](run))
+		offset = start
+	}
+	return append(runs, wrapTree[MS, V, M](rest))
+}
+
+// Return the permutation of indexes that sorts the tree's values by less, leaving the
+// tree alone. Equal values keep their original order.
+func (t FingerTree[MS, V, M]) ArgSort(less func(V, V) bool) []int {
+	values := t.ToSlice()
+	perm := make([]int, len(values))
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(i, j int) bool {
+		return less(values[perm[i]], values[perm[j]])
+	})
+	return perm
+}
+
+// Return how many leading values of a and b are equal by eq, iterating both trees
+// together and stopping at the first difference.
+func CommonPrefixLen[MS Measurer[V, M], V, M any](a, b FingerTree[MS, V, M], eq func(V, V) bool) int {
+	ca, cb := newCursor(a.tree()), newCursor(b.tree())
+	count := 0
+	for {
+		va, okA := ca.next()
+		vb, okB := cb.next()
+		if !okA || !okB || !eq(va.(V), vb.(V)) {
+			return count
+		}
+		count++
+	}
+}
+
+// Merge the sorted tree with a sorted slice into a sorted tree. Values from the tree
+// come before equal values from the slice. Each slice value is placed with a binary
+// search and the runs of tree values between them are spliced in with splits, so
+// this is O(m log² n) for m slice values rather than walking the whole tree.
+func (t FingerTree[MS, V, M]) MergeSlice(sorted []V, less func(V, V) bool) FingerTree[MS, V, M] {
+	result := empty(t.tree())
+	rest := t.tree()
+	for _, v := range sorted {
+		// count the leading values in rest that are <= v
+		low, high := 0, rest.size()
+		for low < high {
+			mid := (low + high) / 2
+			if less(v, valueAt(rest, mid).(V)) {
+				high = mid
+			} else {
+				low = mid + 1
+			}
+		}
+		var run fingerTree
+		run, rest = splitAt(rest, low)
+		result = result.Concat(run).AddLast(v)
+	}
+	return wrapTree[MS, V, M](result.Concat(rest))
+}
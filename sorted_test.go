@@ -0,0 +1,140 @@
+package lazyfingertree
+
+import "testing"
+
+func intLess(a, b int) bool {
+	return a < b
+}
+
+func TestFirstDescent(t *testing.T) {
+	for _, test := range []struct {
+		values []int
+		index  int
+		ok     bool
+	}{
+		{[]int{1, 2, 2, 3, 5}, -1, false},
+		{[]int{}, -1, false},
+		{[]int{4}, -1, false},
+		{[]int{5, 1, 2, 3}, 1, true},
+		{[]int{1, 2, 3, 4, 0}, 4, true},
+	} {
+		index, ok := newTree(test.values...).FirstDescent(intLess)
+		failIfNot(t, index == test.index && ok == test.ok)
+	}
+}
+
+func TestOverlapCount(t *testing.T) {
+	a := newTree(1, 3, 5, 7, 9)
+	failIfNot(t, OverlapCount(a, newTree(1, 3, 5, 7, 9), intLess) == 5)
+	failIfNot(t, OverlapCount(a, newTree(2, 4, 6, 8, 10), intLess) == 0)
+	failIfNot(t, OverlapCount(a, newTree(0, 3, 4, 9, 12), intLess) == 2)
+	failIfNot(t, OverlapCount(newTree(1, 1, 2), newTree(1, 2, 2), intLess) == 2)
+	failIfNot(t, OverlapCount(a, newTree[int](), intLess) == 0)
+}
+
+func TestMergeSorted(t *testing.T) {
+	merged := Merge(newTree(1, 4, 4, 9), newTree(0, 4, 5, 10, 11), intLess)
+	failIfNot(t, same(merged.ToSlice(), []int{0, 1, 4, 4, 4, 5, 9, 10, 11}))
+	failIfNot(t, merged.Measure() == 9)
+	type pair struct{ key, from int }
+	pairLess := func(a, b pair) bool { return a.key < b.key }
+	stable := Merge(newTree(pair{1, 0}, pair{2, 0}), newTree(pair{1, 1}, pair{2, 1}), pairLess)
+	failIfNot(t, same(stable.ToSlice(), []pair{{1, 0}, {1, 1}, {2, 0}, {2, 1}}))
+}
+
+func TestConcatSorted(t *testing.T) {
+	joined, fast := newTree(1, 2, 3).ConcatSorted(newTree(3, 4, 5), intLess)
+	failIfNot(t, fast && same(joined.ToSlice(), []int{1, 2, 3, 3, 4, 5}))
+	joined, fast = newTree(1, 3, 5).ConcatSorted(newTree(2, 4, 6), intLess)
+	failIfNot(t, !fast && same(joined.ToSlice(), []int{1, 2, 3, 4, 5, 6}))
+	joined, fast = newTree[int]().ConcatSorted(newTree(2, 4), intLess)
+	failIfNot(t, fast && same(joined.ToSlice(), []int{2, 4}))
+}
+
+func TestSortedPrefix(t *testing.T) {
+	for _, test := range []struct {
+		values, prefix, rest []int
+	}{
+		{[]int{1, 2, 2, 5}, []int{1, 2, 2, 5}, []int{}},
+		{[]int{5, 1, 2}, []int{5}, []int{1, 2}},
+		{[]int{1, 3, 4, 2, 6, 7}, []int{1, 3, 4}, []int{2, 6, 7}},
+		{[]int{}, []int{}, []int{}},
+	} {
+		prefix, rest := newTree(test.values...).SortedPrefix(intLess)
+		failIfNot(t, same(prefix.ToSlice(), test.prefix) && same(rest.ToSlice(), test.rest))
+	}
+}
+
+func TestArgSort(t *testing.T) {
+	tree := newTree(5, 3, 9, 3, 1, 7, 5)
+	perm := tree.ArgSort(intLess)
+	values := tree.ToSlice()
+	sorted := make([]int, len(perm))
+	for i, p := range perm {
+		sorted[i] = values[p]
+	}
+	failIfNot(t, same(sorted, []int{1, 3, 3, 5, 5, 7, 9}))
+	failIfNot(t, same(perm, []int{4, 1, 3, 0, 6, 5, 2}))
+	failIfNot(t, same(tree.ToSlice(), []int{5, 3, 9, 3, 1, 7, 5}))
+	failIfNot(t, len(newTree[int]().ArgSort(intLess)) == 0)
+}
+
+func runSlices(runs []FingerTree[width[int, int], int, int]) [][]int {
+	result := make([][]int, len(runs))
+	for i, run := range runs {
+		result[i] = run.ToSlice()
+	}
+	return result
+}
+
+func TestAscendingRuns(t *testing.T) {
+	sorted := runSlices(newTree(1, 2, 2, 3, 8).AscendingRuns(intLess))
+	failIfNot(t, len(sorted) == 1 && same(sorted[0], []int{1, 2, 2, 3, 8}))
+	reversed := runSlices(newTree(5, 4, 3, 2, 1).AscendingRuns(intLess))
+	failIfNot(t, len(reversed) == 5)
+	for i, run := range reversed {
+		failIfNot(t, same(run, []int{5 - i}))
+	}
+	mixed := runSlices(newTree(1, 3, 3, 2, 5, 9, 0, 4, 1).AscendingRuns(intLess))
+	failIfNot(t, len(mixed) == 4)
+	failIfNot(t, same(mixed[0], []int{1, 3, 3}) && same(mixed[1], []int{2, 5, 9}))
+	failIfNot(t, same(mixed[2], []int{0, 4}) && same(mixed[3], []int{1}))
+	failIfNot(t, len(newTree[int]().AscendingRuns(intLess)) == 0)
+}
+
+func TestCommonPrefixLen(t *testing.T) {
+	eq := func(a, b int) bool { return a == b }
+	failIfNot(t, CommonPrefixLen(newTree(1, 2, 3), newTree(1, 2, 3), eq) == 3)
+	failIfNot(t, CommonPrefixLen(newTree(1, 2), newTree(1, 2, 3, 4), eq) == 2)
+	failIfNot(t, CommonPrefixLen(newTree(1, 2, 3, 4), newTree(1, 2), eq) == 2)
+	failIfNot(t, CommonPrefixLen(newTree(9, 2, 3), newTree(1, 2, 3), eq) == 0)
+	failIfNot(t, CommonPrefixLen(newTree(1, 2, 5, 4), newTree(1, 2, 3, 4), eq) == 2)
+	failIfNot(t, CommonPrefixLen(newTree[int](), newTree(1), eq) == 0)
+}
+
+func TestMergeSlice(t *testing.T) {
+	merged := newTree(1, 4, 4, 9).MergeSlice([]int{0, 4, 5, 10, 11}, intLess)
+	failIfNot(t, same(merged.ToSlice(), []int{0, 1, 4, 4, 4, 5, 9, 10, 11}))
+	failIfNot(t, merged.Measure() == 9)
+	failIfNot(t, same(newTree[int]().MergeSlice([]int{1, 2}, intLess).ToSlice(), []int{1, 2}))
+	failIfNot(t, same(newTree(1, 2).MergeSlice(nil, intLess).ToSlice(), []int{1, 2}))
+	type pair struct{ key, from int }
+	pairLess := func(a, b pair) bool { return a.key < b.key }
+	stable := newTree(pair{1, 0}, pair{2, 0}).MergeSlice([]pair{{1, 1}, {2, 1}}, pairLess)
+	failIfNot(t, same(stable.ToSlice(), []pair{{1, 0}, {1, 1}, {2, 0}, {2, 1}}))
+	evens := make([]int, 0, 1000)
+	for i := 0; i < 2000; i += 2 {
+		evens = append(evens, i)
+	}
+	big := newTree(evens...).MergeSlice([]int{-1, 7, 7, 999, 5000}, intLess)
+	expected := append([]int{-1}, evens[:4]...)
+	expected = append(expected, 7, 7)
+	expected = append(expected, evens[4:500]...)
+	expected = append(expected, 999)
+	expected = append(expected, evens[500:]...)
+	expected = append(expected, 5000)
+	failIfNot(t, same(big.ToSlice(), expected) && big.Measure() == len(expected))
+	for i, v := range expected {
+		failIfNot(t, big.Get(i) == v)
+	}
+}
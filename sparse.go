@@ -0,0 +1,95 @@
+package lazyfingertree
+
+import (
+	"cmp"
+	"iter"
+)
+
+// A SparseSeq is a persistent sequence over a huge index space where most indexes
+// are absent. It stores only the present entries, ordered by index and measured by
+// the largest index, so operations take O(log n) in the number of entries.
+type SparseSeq[V any] struct {
+	tree KeyedTree[SparseEntry[V], int]
+}
+
+// A SparseEntry is a present index in a SparseSeq and its value.
+type SparseEntry[V any] struct {
+	Index int
+	Value V
+}
+
+func sparseIndex[V any](e SparseEntry[V]) int {
+	return e.Index
+}
+
+// Create an empty sparse sequence.
+func NewSparseSeq[V any]() SparseSeq[V] {
+	return SparseSeq[V]{FromArray(KeyMeasurer[SparseEntry[V], int]{sparseIndex[V], cmp.Compare[int]}, []SparseEntry[V]{})}
+}
+
+// Return the number of present entries.
+func (s SparseSeq[V]) Len() int {
+	return s.tree.Len()
+}
+
+// Return the value at index and whether it is present.
+func (s SparseSeq[V]) Get(index int) (V, bool) {
+	if e, ok := Ceiling(s.tree, index); ok && e.Index == index {
+		return e.Value, true
+	}
+	return null[V](), false
+}
+
+// Return a sequence with value at index.
+func (s SparseSeq[V]) Set(index int, value V) SparseSeq[V] {
+	tree, _ := UpsertOrdered(s.tree, SparseEntry[V]{index, value})
+	return SparseSeq[V]{tree}
+}
+
+// Return a sequence without an entry at index.
+func (s SparseSeq[V]) Delete(index int) SparseSeq[V] {
+	left, right := s.splitAt(index)
+	return SparseSeq[V]{left.Concat(right)}
+}
+
+// Split around index, dropping its entry if it is present.
+func (s SparseSeq[V]) splitAt(index int) (KeyedTree[SparseEntry[V], int], KeyedTree[SparseEntry[V], int]) {
+	left, right := s.tree.Split(s.tree.measurer().AtLeast(index))
+	if !right.IsEmpty() && right.PeekFirst().Index == index {
+		right = right.RemoveFirst()
+	}
+	return left, right
+}
+
+// Return the first present entry with an index greater than index.
+func (s SparseSeq[V]) NextAfter(index int) (int, V, bool) {
+	loc, ok := locateKey(s.tree, s.tree.measurer().Above(index))
+	if !ok {
+		return 0, null[V](), false
+	}
+	e := loc.value.(SparseEntry[V])
+	return e.Index, e.Value, true
+}
+
+// Return the present entries in index order.
+func (s SparseSeq[V]) All() iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		s.tree.Each(func(e SparseEntry[V]) bool {
+			return yield(e.Index, e.Value)
+		})
+	}
+}
+
+// Return the present entries with indexes from start up to end, in index order.
+// This finds start in one descent and stops at end without visiting later entries.
+func (s SparseSeq[V]) Range(start, end int) iter.Seq2[int, V] {
+	return func(yield func(int, V) bool) {
+		if start >= end {
+			return
+		}
+		_, right := s.tree.Split(s.tree.measurer().AtLeast(start))
+		right.Each(func(e SparseEntry[V]) bool {
+			return e.Index < end && yield(e.Index, e.Value)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package lazyfingertree
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func sparseIndexes(seq func(func(int, string) bool)) []int {
+	indexes := []int{}
+	for i := range seq {
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+func TestSparseSeq(t *testing.T) {
+	seq := NewSparseSeq[string]().Set(1_000_000_000, "big").Set(-5, "neg").Set(7, "seven").Set(7, "SEVEN")
+	failIfNot(t, seq.Len() == 3)
+	v, ok := seq.Get(7)
+	failIfNot(t, ok && v == "SEVEN")
+	_, ok = seq.Get(8)
+	failIfNot(t, !ok)
+	i, v, ok := seq.NextAfter(7)
+	failIfNot(t, ok && i == 1_000_000_000 && v == "big")
+	i, _, ok = seq.NextAfter(-100)
+	failIfNot(t, ok && i == -5)
+	_, _, ok = seq.NextAfter(1_000_000_000)
+	failIfNot(t, !ok)
+	failIfNot(t, slices.Equal(sparseIndexes(seq.All()), []int{-5, 7, 1_000_000_000}))
+	failIfNot(t, slices.Equal(sparseIndexes(seq.Range(-5, 1_000_000_000)), []int{-5, 7}))
+	failIfNot(t, slices.Equal(sparseIndexes(seq.Range(-4, 8)), []int{7}))
+	failIfNot(t, len(sparseIndexes(seq.Range(8, 8))) == 0)
+	deleted := seq.Delete(7).Delete(12)
+	_, ok = deleted.Get(7)
+	failIfNot(t, !ok && deleted.Len() == 2 && seq.Len() == 3)
+}
+
+func TestSparseSeqRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	seq := NewSparseSeq[string]()
+	want := map[int]string{}
+	for n := 0; n < 1000; n++ {
+		index := rng.Intn(200) * 1000
+		if rng.Intn(3) == 0 {
+			seq = seq.Delete(index)
+			delete(want, index)
+		} else {
+			seq = seq.Set(index, string(rune('a'+n%26)))
+			want[index] = string(rune('a' + n%26))
+		}
+	}
+	failIfNot(t, seq.Len() == len(want))
+	failIfNot(t, slices.Equal(sparseIndexes(seq.All()), slices.Sorted(maps.Keys(want))))
+	for index := -1000; index < 201000; index += 500 {
+		v, ok := seq.Get(index)
+		wv, wok := want[index]
+		failIfNot(t, ok == wok && v == wv)
+	}
+}
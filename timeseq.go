@@ -0,0 +1,69 @@
+package lazyfingertree
+
+import (
+	"fmt"
+	"iter"
+	"time"
+)
+
+var ErrOutOfOrder = fmt.Errorf("%w, value out of order", ErrFingerTree)
+
+// A TimeSeq is a persistent sequence of values in timestamp order, like an event log.
+// It is measured by the latest timestamp so range queries take one descent.
+type TimeSeq[V any] struct {
+	tree KeyedTree[V, time.Time]
+}
+
+// Create an empty sequence that gets each value's timestamp with timestamp.
+func NewTimeSeq[V any](timestamp func(V) time.Time) TimeSeq[V] {
+	return TimeSeq[V]{FromArray(KeyMeasurer[V, time.Time]{timestamp, compareTimes}, []V{})}
+}
+
+func compareTimes(a, b time.Time) int {
+	return a.Compare(b)
+}
+
+// Return the number of values in the sequence.
+func (s TimeSeq[V]) Len() int {
+	return s.tree.Len()
+}
+
+// Return the values in timestamp order.
+func (s TimeSeq[V]) Tree() KeyedTree[V, time.Time] {
+	return s.tree
+}
+
+// Return a sequence with value added to the end. This returns an ErrOutOfOrder error
+// if value's timestamp is before the latest one. Equal timestamps are allowed.
+func (s TimeSeq[V]) Append(value V) (TimeSeq[V], error) {
+	meas := s.tree.measurer()
+	if latest := s.tree.Measure(); latest.Valid && meas.Key(value).Before(latest.Key) {
+		return s, fmt.Errorf("%w: %v is before %v", ErrOutOfOrder, meas.Key(value), latest.Key)
+	}
+	return TimeSeq[V]{s.tree.AddLast(value)}, nil
+}
+
+// Return the values with timestamps at or after start and before end, in order.
+func (s TimeSeq[V]) Between(start, end time.Time) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		meas := s.tree.measurer()
+		_, rest := s.tree.Split(meas.AtLeast(start))
+		rest.Each(func(v V) bool {
+			return meas.Key(v).Before(end) && yield(v)
+		})
+	}
+}
+
+// Return a sequence without the values whose timestamps are before t.
+func (s TimeSeq[V]) DropBefore(t time.Time) TimeSeq[V] {
+	_, rest := s.tree.Split(s.tree.measurer().AtLeast(t))
+	return TimeSeq[V]{rest}
+}
+
+// Return the last value, which has the latest timestamp.
+func (s TimeSeq[V]) Latest() (V, bool) {
+	if s.tree.IsEmpty() {
+		return null[V](), false
+	}
+	return s.tree.PeekLast(), true
+}
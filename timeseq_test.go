@@ -0,0 +1,57 @@
+package lazyfingertree
+
+import (
+	"errors"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+)
+
+type event struct {
+	at   time.Time
+	name string
+}
+
+func eventTime(e event) time.Time {
+	return e.at
+}
+
+func eventNames(seq iter.Seq[event]) []string {
+	names := []string{}
+	for e := range seq {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+func TestTimeSeq(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	seq := NewTimeSeq(eventTime)
+	_, ok := seq.Latest()
+	failIfNot(t, !ok && seq.Len() == 0)
+	var err error
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		seq, err = seq.Append(event{at(i * 10), name})
+		failIfNot(t, err == nil)
+	}
+	seq, err = seq.Append(event{at(40), "f"})
+	failIfNot(t, err == nil && seq.Len() == 6)
+	unchanged, err := seq.Append(event{at(5), "late"})
+	failIfNot(t, errors.Is(err, ErrOutOfOrder) && unchanged.Len() == 6)
+	latest, ok := seq.Latest()
+	failIfNot(t, ok && latest.name == "f")
+	failIfNot(t, slices.Equal(eventNames(seq.Between(at(10), at(30))), []string{"b", "c"}))
+	failIfNot(t, slices.Equal(eventNames(seq.Between(at(15), at(41))), []string{"c", "d", "e", "f"}))
+	failIfNot(t, slices.Equal(eventNames(seq.Between(at(-10), at(0))), []string{}))
+	failIfNot(t, slices.Equal(eventNames(seq.Between(at(30), at(10))), []string{}))
+	failIfNot(t, slices.Equal(eventNames(seq.Between(at(0), at(1))), []string{"a"}))
+	for e := range seq.Between(at(0), at(100)) {
+		failIfNot(t, e.name == "a")
+		break
+	}
+	dropped := seq.DropBefore(at(30))
+	failIfNot(t, slices.Equal(eventNames(slices.Values(dropped.Tree().ToSlice())), []string{"d", "e", "f"}))
+	failIfNot(t, seq.DropBefore(at(100)).Len() == 0 && seq.DropBefore(at(0)).Len() == 6)
+}
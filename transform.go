@@ -0,0 +1,272 @@
+package lazyfingertree
+
+import "fmt"
+
+// Partition the tree into the values that satisfy pred and the ones that do not,
+// preserving their order. The measures of both trees are accumulated during the
+// same pass and returned along with them.
+func (t FingerTree[MS, V, M]) StablePartition(pred func(V) bool) (FingerTree[MS, V, M], FingerTree[MS, V, M], M, M) {
+	meas := measurerFor(t.tree())
+	yes, no := empty(t.tree()), empty(t.tree())
+	yesMeasure, noMeasure := meas.Identity(), meas.Identity()
+	t.tree().Each(wrapIter(func(v V) bool {
+		if pred(v) {
+			yes = yes.AddLast(v)
+			yesMeasure = meas.Sum(yesMeasure, meas.Measure(v))
+		} else {
+			no = no.AddLast(v)
+			noMeasure = meas.Sum(noMeasure, meas.Measure(v))
+		}
+		return true
+	}))
+	return wrapTree[MS, V, M](yes), wrapTree[MS, V, M](no), yesMeasure.(M), noMeasure.(M)
+}
+
+// Merge two trees by alternating their values, a0, b0, a1, b1, ..., followed by the
+// rest of the longer tree.
+func Interleave[MS Measurer[V, M], V, M any](a, b FingerTree[MS, V, M]) FingerTree[MS, V, M] {
+	builder := newBuilder[MS, V, M](measurerFor(a.tree()))
+	ca, cb := newCursor(a.tree()), newCursor(b.tree())
+	for {
+		va, okA := ca.next()
+		if okA {
+			builder.Add(va.(V))
+		}
+		vb, okB := cb.next()
+		if okB {
+			builder.Add(vb.(V))
+		}
+		if !okA && !okB {
+			return builder.Tree()
+		}
+	}
+}
+
+// Combine two trees value by value through f, building the result with measurer.
+// The result is as long as the shorter tree and neither tree is traversed past that.
+func ZipWith[MS1 Measurer[V1, M1], MS2 Measurer[V2, M2], MS3 Measurer[V3, M3], V1, M1, V2, M2, V3, M3 any](
+	a FingerTree[MS1, V1, M1], b FingerTree[MS2, V2, M2], measurer MS3, f func(V1, V2) V3,
+) FingerTree[MS3, V3, M3] {
+	builder := NewBuilder[MS3, V3, M3](measurer)
+	ca, cb := newCursor(a.tree()), newCursor(b.tree())
+	for ca.more() && cb.more() {
+		va, _ := ca.next()
+		vb, _ := cb.next()
+		builder.Add(f(va.(V1), vb.(V2)))
+	}
+	return builder.Tree()
+}
+
+// Return a tree of the running results of folding f over the tree, measured by measurer.
+// The result has one value per value in the tree: the accumulator after folding in that
+// value. The initial accumulator is not included.
+func Scan[MSA Measurer[A, MA], MS Measurer[V, M], V, M, A, MA any](t FingerTree[MS, V, M], init A, f func(A, V) A, measurer MSA) FingerTree[MSA, A, MA] {
+	builder := NewBuilder[MSA, A, MA](measurer)
+	acc := init
+	t.Each(func(v V) bool {
+		acc = f(acc, v)
+		builder.Add(acc)
+		return true
+	})
+	return builder.Tree()
+}
+
+// Return a tree holding the largest value seen so far at each position (the prefix maximum),
+// measured by measurer.
+func RunningMax[MS2 Measurer[V, M2], MS Measurer[V, M], V, M, M2 any](t FingerTree[MS, V, M], measurer MS2, greater func(V, V) bool) FingerTree[MS2, V, M2] {
+	if t.IsEmpty() {
+		return NewBuilder[MS2, V, M2](measurer).Tree()
+	}
+	return Scan(t, t.PeekFirst(), func(max V, v V) V {
+		if greater(v, max) {
+			return v
+		}
+		return max
+	}, measurer)
+}
+
+// Return a tree holding the inclusive prefix measure at each position, so each value
+// becomes the measure of itself and everything before it, measured by measurer.
+func CumulativeMeasureTree[MS2 Measurer[M, M2], MS Measurer[V, M], V, M, M2 any](t FingerTree[MS, V, M], measurer MS2) FingerTree[MS2, M, M2] {
+	if t.IsEmpty() {
+		return NewBuilder[MS2, M, M2](measurer).Tree()
+	}
+	meas := t.measurer()
+	return Scan(t, meas.Identity(), func(acc M, v V) M {
+		return meas.Sum(acc, meas.Measure(v))
+	}, measurer)
+}
+
+// Map each value through f while threading an accumulator from left to right.
+// Returns the final accumulator and the tree of mapped values, measured by measurer.
+func MapAccum[MS2 Measurer[V2, M2], MS Measurer[V, M], V, M, A, V2, M2 any](t FingerTree[MS, V, M], init A, f func(A, V) (A, V2), measurer MS2) (A, FingerTree[MS2, V2, M2]) {
+	builder := NewBuilder[MS2, V2, M2](measurer)
+	acc := init
+	t.Each(func(v V) bool {
+		var v2 V2
+		acc, v2 = f(acc, v)
+		builder.Add(v2)
+		return true
+	})
+	return acc, builder.Tree()
+}
+
+// A Run is a value repeated Count times.
+type Run[V any] struct {
+	Value V
+	Count int
+}
+
+// Return the runs of consecutive equal values in the tree.
+func RunLengthEncode[MS Measurer[V, M], V comparable, M any](t FingerTree[MS, V, M]) []Run[V] {
+	var runs []Run[V]
+	t.Each(func(v V) bool {
+		if len(runs) > 0 && runs[len(runs)-1].Value == v {
+			runs[len(runs)-1].Count++
+		} else {
+			runs = append(runs, Run[V]{v, 1})
+		}
+		return true
+	})
+	return runs
+}
+
+// Build a tree from runs of values, the inverse of RunLengthEncode.
+func RunLengthDecode[MS Measurer[V, M], V, M any](measurer MS, runs []Run[V]) FingerTree[MS, V, M] {
+	builder := NewBuilder[MS, V, M](measurer)
+	for _, run := range runs {
+		for i := 0; i < run.Count; i++ {
+			builder.Add(run.Value)
+		}
+	}
+	return builder.Tree()
+}
+
+// Split the tree into the segments between values where isDelim is true, dropping
+// the delimiters, like strings.Split. Leading, trailing, and consecutive delimiters
+// produce empty segments, so n delimiters always give n+1 segments. Use [Fields] to
+// drop the empty segments instead.
+func (t FingerTree[MS, V, M]) SplitOn(isDelim func(V) bool) []FingerTree[MS, V, M] {
+	return t.splitOn(isDelim, false)
+}
+
+// Split the tree into the non-empty segments between values where isDelim is true,
+// dropping the delimiters, like strings.FieldsFunc.
+func (t FingerTree[MS, V, M]) Fields(isDelim func(V) bool) []FingerTree[MS, V, M] {
+	return t.splitOn(isDelim, true)
+}
+
+func (t FingerTree[MS, V, M]) splitOn(isDelim func(V) bool, collapse bool) []FingerTree[MS, V, M] {
+	var delims []int
+	index := 0
+	t.Each(func(v V) bool {
+		if isDelim(v) {
+			delims = append(delims, index)
+		}
+		index++
+		return true
+	})
+	var segments []FingerTree[MS, V, M]
+	rest := t.tree()
+	offset := 0
+	for _, delim := range delims {
+		segment, after := splitAt(rest, delim-offset)
+		if !collapse || !isEmpty(segment) {
+			segments = append(segments, wrapTree[MS, V, M](segment))
+		}
+		rest = after.RemoveFirst()
+		offset = delim + 1
+	}
+	if !collapse || !isEmpty(rest) {
+		segments = append(segments, wrapTree[MS, V, M](rest))
+	}
+	return segments
+}
+
+// Return a tree of the values at indexes 0, n, 2n, ..., for downsampling.
+// This panics if n < 1.
+func (t FingerTree[MS, V, M]) EveryNth(n int) FingerTree[MS, V, M] {
+	if n < 1 {
+		panic(fmt.Errorf("%w: EveryNth step %d", ErrOutOfRange, n))
+	}
+	builder := newBuilder[MS, V, M](measurerFor(t.tree()))
+	index := 0
+	t.Each(func(v V) bool {
+		if index%n == 0 {
+			builder.Add(v)
+		}
+		index++
+		return true
+	})
+	return builder.Tree()
+}
+
+// Merge runs of neighboring values into single values, for defragmenting trees of
+// chunks. Each value is merged into the one before it with merge while canMerge
+// allows, and the result is built in bulk in one pass, measuring the merged values.
+// Empty and single-value trees are returned unchanged.
+func (t FingerTree[MS, V, M]) CoalesceAdjacent(canMerge func(a, b V) bool, merge func(a, b V) V) FingerTree[MS, V, M] {
+	if t.tree().size() < 2 {
+		return t
+	}
+	builder := newBuilder[MS, V, M](measurerFor(t.tree()))
+	var cur V
+	first := true
+	t.Each(func(v V) bool {
+		if first {
+			cur, first = v, false
+		} else if canMerge(cur, v) {
+			cur = merge(cur, v)
+		} else {
+			builder.Add(cur)
+			cur = v
+		}
+		return true
+	})
+	builder.Add(cur)
+	return builder.Tree()
+}
+
+// Return the largest value in each consecutive window of values, in one pass with a
+// monotonic deque. A tree shorter than window has no windows. This panics if window < 1.
+func (t FingerTree[MS, V, M]) SlidingMax(window int, greater func(V, V) bool) []V {
+	if window < 1 {
+		panic(fmt.Errorf("%w: SlidingMax window %d", ErrOutOfRange, window))
+	}
+	type entry struct {
+		index int
+		value V
+	}
+	var result []V
+	// values in the deque decrease from front to back and the front is the max
+	var deque []entry
+	index := 0
+	t.Each(func(v V) bool {
+		for len(deque) > 0 && !greater(deque[len(deque)-1].value, v) {
+			deque = deque[:len(deque)-1]
+		}
+		deque = append(deque, entry{index, v})
+		if deque[0].index <= index-window {
+			deque = deque[1:]
+		}
+		if index >= window-1 {
+			result = append(result, deque[0].value)
+		}
+		index++
+		return true
+	})
+	return result
+}
+
+// Return a tree of the values that satisfy keep, in order.
+func (t FingerTree[MS, V, M]) Filter(keep func(V) bool) FingerTree[MS, V, M] {
+	meas := measurerFor(t.tree())
+	builder := newBuilder[MS, V, M](meas)
+	t.Each(func(v V) bool {
+		if keep(v) {
+			builder.Add(v)
+		}
+		return true
+	})
+	return wrapTree[MS, V, M](normalizeEmpty(builder.Tree().f, meas))
+}
@@ -0,0 +1,121 @@
+package lazyfingertree
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var ErrInconsistentMeasure = fmt.Errorf("%w, inconsistent cached measure", ErrFingerTree)
+
+// Recompute the measure of every part of the tree from its values and compare them
+// to the cached measures, returning an ErrInconsistentMeasure error for the first
+// one that differs. This forces the whole tree and is meant for testing.
+func MeasuresConsistent[MS Measurer[V, M], V, M any](t FingerTree[MS, V, M]) error {
+	_, _, err := checkTree(measurerFor(t.tree()), t.tree(), 0)
+	return err
+}
+
+// Return a copy of the tree with every cached measure and size recomputed from its
+// values under the tree's measurer, keeping the same shape. This repairs trees whose
+// cached measures are stale, like ones reconstructed from an external format, and
+// forces the whole tree.
+func (t FingerTree[MS, V, M]) RecomputeMeasures() FingerTree[MS, V, M] {
+	return wrapTree[MS, V, M](rebuildTree(measurerFor(t.tree()), t.tree()))
+}
+
+func rebuildTree(meas measurer, tree fingerTree) fingerTree {
+	switch tr := force(tree).(type) {
+	case *singleTree:
+		return newSingleTree(meas, rebuildItem(meas, tr.value))
+	case *deepTree:
+		d := newDeepTree(meas,
+			newDigit(meas, rebuildItems(meas, tr.left.items)),
+			rebuildTree(newNodeMeasurer(meas), tr.mid),
+			newDigit(meas, rebuildItems(meas, tr.right.items)))
+		d.measurement()
+		return d
+	}
+	return newEmptyTree(meas)
+}
+
+func rebuildItems(meas measurer, items []any) []any {
+	result := make([]any, len(items))
+	for i, item := range items {
+		result[i] = rebuildItem(meas, item)
+	}
+	return result
+}
+
+// Meas is the measurer for the layer holding item, so a node's children are
+// measured with the measurer it wraps.
+func rebuildItem(meas measurer, item any) any {
+	if n, ok := item.(*node); ok {
+		inner := meas.(nodeMeasurer).measurer
+		return newNode(inner, rebuildItems(inner, n.children))
+	}
+	return item
+}
+
+func checkTree(meas measurer, tree fingerTree, depth int) (any, int, error) {
+	switch tr := force(tree).(type) {
+	case *emptyTree:
+		return meas.Identity(), 0, nil
+	case *singleTree:
+		m, size, err := checkItem(meas, tr.value, depth)
+		if err != nil {
+			return nil, 0, err
+		}
+		return m, size, checkMeasure("single tree", depth, tr._measurement.value, m, tr.size(), size)
+	case *deepTree:
+		cached := tr.cache.Load()
+		lm, lsize, err := checkItems(meas, "left digit", tr.left._measurement.value, tr.left._size, tr.left.items, depth)
+		if err != nil {
+			return nil, 0, err
+		}
+		mm, msize, err := checkTree(meas, tr.mid, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		rm, rsize, err := checkItems(meas, "right digit", tr.right._measurement.value, tr.right._size, tr.right.items, depth)
+		if err != nil {
+			return nil, 0, err
+		}
+		m := meas.Sum(meas.Sum(lm, mm), rm)
+		size := lsize + msize + rsize
+		if cached != nil {
+			return m, size, checkMeasure("deep tree", depth, cached.value, m, cached.size, size)
+		}
+		return m, size, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unknown tree %T at depth %d", ErrInconsistentMeasure, tree, depth)
+}
+
+func checkItems(meas measurer, kind string, cached any, cachedSize int, items []any, depth int) (any, int, error) {
+	m := meas.Identity()
+	size := 0
+	for _, item := range items {
+		im, isize, err := checkItem(meas, item, depth)
+		if err != nil {
+			return nil, 0, err
+		}
+		m = meas.Sum(m, im)
+		size += isize
+	}
+	return m, size, checkMeasure(kind, depth, cached, m, cachedSize, size)
+}
+
+func checkItem(meas measurer, item any, depth int) (any, int, error) {
+	if n, ok := item.(*node); ok {
+		return checkItems(meas, "node", n._measurement.value, n._size, n.children, depth)
+	}
+	return meas.Measure(item), 1, nil
+}
+
+func checkMeasure(kind string, depth int, cached, computed any, cachedSize, size int) error {
+	if !reflect.DeepEqual(cached, computed) {
+		return fmt.Errorf("%w: %s at depth %d has %v but its values measure %v", ErrInconsistentMeasure, kind, depth, cached, computed)
+	} else if cachedSize != size {
+		return fmt.Errorf("%w: %s at depth %d has size %d but holds %d values", ErrInconsistentMeasure, kind, depth, cachedSize, size)
+	}
+	return nil
+}
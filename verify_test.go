@@ -0,0 +1,88 @@
+package lazyfingertree
+
+import (
+	"errors"
+	"testing"
+)
+
+func FuzzMeasuresConsistent(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	f.Add([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 5, 7, 3, 1})
+	f.Add([]byte{6, 6, 6, 6, 6, 6, 6, 6, 5, 5, 5, 5, 4, 200, 3, 1, 7})
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		tree := FromArray(sumValues(0), []int{})
+		expected := []int{}
+		for i, op := range ops {
+			switch op % 8 {
+			case 0, 1:
+				tree = tree.AddLast(i)
+				expected = append(expected, i)
+			case 2:
+				tree = tree.AddFirst(i)
+				expected = append([]int{i}, expected...)
+			case 3:
+				if !tree.IsEmpty() {
+					tree = tree.RemoveFirst()
+					expected = expected[1:]
+				}
+			case 4:
+				if !tree.IsEmpty() {
+					tree = tree.RemoveLast()
+					expected = expected[:len(expected)-1]
+				}
+			case 5:
+				pos := int(op) % (len(expected) + 1)
+				left, right := splitAt(tree.f, pos)
+				tree = wrapTree[sumValues, int, int](right.Concat(left))
+				expected = append(Dup(expected[pos:]), expected[:pos]...)
+			case 6:
+				values := []int{i, i + 1, i + 2, i + 3, i + 4}
+				tree = tree.Concat(FromArray(sumValues(0), values))
+				expected = append(expected, values...)
+			case 7:
+				left, right := tree.SplitHalf()
+				tree = right.Concat(left)
+				expected = append(Dup(expected[(len(expected)+1)/2:]), expected[:(len(expected)+1)/2]...)
+			}
+			if err := MeasuresConsistent(tree); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !same(tree.ToSlice(), expected) {
+			t.Fatal("wrong values")
+		}
+	})
+}
+
+func TestMeasuresInconsistent(t *testing.T) {
+	tree := FromArray(sumValues(0), []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	failIfErrNow(t, MeasuresConsistent(tree))
+	d := force(tree.f).(*deepTree)
+	d.left._measurement.value = 100
+	failIfNot(t, errors.Is(MeasuresConsistent(tree), ErrInconsistentMeasure))
+}
+
+func TestRecomputeMeasures(t *testing.T) {
+	values := make([]int, 200)
+	for i := range values {
+		values[i] = i
+	}
+	tree := FromArray(sumValues(0), values)
+	d := force(tree.f).(*deepTree)
+	d.left._measurement.value = 100
+	d.cache.Store(nil)
+	mid := force(d.mid).(*deepTree)
+	n := mid.right.items[0].(*node)
+	n._measurement.value = -5
+	n._size = 1
+	failIfNot(t, errors.Is(MeasuresConsistent(tree), ErrInconsistentMeasure))
+	repaired := tree.RecomputeMeasures()
+	failIfErrNow(t, MeasuresConsistent(repaired))
+	failIfNot(t, repaired.Measure() == 199*200/2 && repaired.Len() == 200)
+	failIfNot(t, same(repaired.ToSlice(), values) && repaired.Get(150) == 150)
+	failIfNot(t, errors.Is(MeasuresConsistent(tree), ErrInconsistentMeasure))
+	left, right := repaired.Split(func(m int) bool { return m > 45 })
+	failIfNot(t, left.Len() == 10 && right.PeekFirst() == 10)
+	failIfNot(t, FromArray(sumValues(0), []int{}).RecomputeMeasures().IsEmpty())
+	failIfNot(t, FromArray(sumValues(0), []int{7}).RecomputeMeasures().Measure() == 7)
+}
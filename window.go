@@ -0,0 +1,59 @@
+package lazyfingertree
+
+// A WindowQueue is a sliding window of values. Values are pushed at the back,
+// evicted from the front, and the aggregate of the current window is the tree's
+// measure. Measures are always summed front to back so measurers with
+// non-commutative Sums work correctly.
+type WindowQueue[MS Measurer[V, M], V, M any] struct {
+	tree FingerTree[MS, V, M]
+}
+
+// Create an empty window queue.
+func NewWindowQueue[MS Measurer[V, M], V, M any](measurer MS) *WindowQueue[MS, V, M] {
+	return &WindowQueue[MS, V, M]{FromArray(measurer, []V{})}
+}
+
+// Add a value to the back of the window.
+func (q *WindowQueue[MS, V, M]) Push(value V) {
+	q.tree = q.tree.AddLast(value)
+}
+
+// Evict values from the front of the window while the measure of the evicted portion
+// satisfies pred, returning how many were evicted. For a measure holding the latest
+// timestamp, pred could be "the latest timestamp is older than a minute ago".
+// The predicate must be true for the identity and stay true for a prefix of the window.
+func (q *WindowQueue[MS, V, M]) EvictWhile(pred Predicate[M]) int {
+	evicted, rest := q.tree.Split(func(m M) bool { return !pred(m) })
+	q.tree = rest
+	return evicted.Len()
+}
+
+// Return the aggregate measure of the values in the window.
+func (q *WindowQueue[MS, V, M]) Aggregate() M {
+	return q.tree.Measure()
+}
+
+// Return the number of values in the window.
+func (q *WindowQueue[MS, V, M]) Len() int {
+	return q.tree.Len()
+}
+
+// Return the window's values as a tree.
+func (q *WindowQueue[MS, V, M]) Tree() FingerTree[MS, V, M] {
+	return q.tree
+}
+
+// Add value to the end of the tree and then evict values from the front until the
+// tree's measure no longer satisfies overLimit, returning the new tree and the evicted
+// values. For a measure of byte counts, overLimit could be "more than 64 MB". This
+// finds the eviction point in one descent that sums measures from the back, so
+// overLimit must stay true as values are added to the front. If value is over the
+// limit by itself, it is evicted too and the tree is empty.
+func (t FingerTree[MS, V, M]) PushBackBounded(value V, overLimit Predicate[M]) (FingerTree[MS, V, M], []V) {
+	t = t.AddLast(value)
+	if !overLimit(t.Measure()) {
+		return t, nil
+	}
+	evicted, rest := splitAt(t.tree(), locateSuffix(t.tree(), wrapPredicate(overLimit))+1)
+	return wrapTree[MS, V, M](rest), wrapTree[MS, V, M](evicted).ToSlice()
+}
@@ -0,0 +1,95 @@
+package lazyfingertree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type sample struct {
+	time  int
+	value int
+}
+
+type windowMeasure struct {
+	latest int
+	max    int
+	first  int
+}
+
+// measures samples by their latest time, their max value, and their first value
+// (which makes Sum non-commutative)
+type sampleMeasurer struct{}
+
+func (m sampleMeasurer) Identity() windowMeasure {
+	return windowMeasure{-1, -1, -1}
+}
+
+func (m sampleMeasurer) Measure(s sample) windowMeasure {
+	return windowMeasure{s.time, s.value, s.value}
+}
+
+func (m sampleMeasurer) Sum(a windowMeasure, b windowMeasure) windowMeasure {
+	result := b
+	if a.max > b.max {
+		result.max = a.max
+	}
+	if a.first != -1 {
+		result.first = a.first
+	}
+	return result
+}
+
+func TestWindowQueue(t *testing.T) {
+	q := NewWindowQueue[sampleMeasurer](sampleMeasurer{})
+	values := []int{5, 9, 2, 7, 3, 8, 1, 4, 6, 0}
+	for now, value := range values {
+		q.Push(sample{now, value})
+		q.EvictWhile(func(m windowMeasure) bool { return m.latest <= now-3 })
+		start := now - 2
+		if start < 0 {
+			start = 0
+		}
+		max := -1
+		for _, v := range values[start : now+1] {
+			if v > max {
+				max = v
+			}
+		}
+		agg := q.Aggregate()
+		failIfNot(t, q.Len() == now+1-start)
+		failIfNot(t, agg.max == max && agg.latest == now && agg.first == values[start])
+	}
+	failIfNot(t, q.EvictWhile(func(m windowMeasure) bool { return true }) == 3)
+	failIfNot(t, q.Len() == 0 && q.Aggregate().max == -1)
+}
+
+func TestPushBackBounded(t *testing.T) {
+	overLimit := func(m int) bool { return m > 100 }
+	rng := rand.New(rand.NewSource(8))
+	tree := FromArray(sumValues(0), []int{})
+	kept := []int{}
+	for i := 0; i < 500; i++ {
+		size := rng.Intn(40)
+		var evicted []int
+		tree, evicted = tree.PushBackBounded(size, overLimit)
+		kept = append(kept, size)
+		total := 0
+		for _, v := range kept {
+			total += v
+		}
+		var want []int
+		for total > 100 {
+			want = append(want, kept[0])
+			total -= kept[0]
+			kept = kept[1:]
+		}
+		failIfNot(t, same(evicted, want) && same(tree.ToSlice(), kept) && tree.Measure() <= 100)
+	}
+	tree = FromArray(sumValues(0), []int{10, 20, 30})
+	tree, evicted := tree.PushBackBounded(500, overLimit)
+	failIfNot(t, tree.IsEmpty() && same(evicted, []int{10, 20, 30, 500}))
+	tree, evicted = tree.PushBackBounded(100, overLimit)
+	failIfNot(t, same(tree.ToSlice(), []int{100}) && len(evicted) == 0)
+	tree, evicted = tree.PushBackBounded(1, overLimit)
+	failIfNot(t, same(tree.ToSlice(), []int{1}) && same(evicted, []int{100}))
+}
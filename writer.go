@@ -0,0 +1,68 @@
+package lazyfingertree
+
+// A TreeWriter is an io.Writer that collects bytes into a tree of fixed-size chunks.
+type TreeWriter[MS Measurer[[]byte, M], M any] struct {
+	tree      FingerTree[MS, []byte, M]
+	chunkSize int
+	stage     []byte
+}
+
+// Create a TreeWriter that collects written bytes into chunks of chunkSize bytes.
+func NewTreeWriter[MS Measurer[[]byte, M], M any](measurer MS, chunkSize int) *TreeWriter[MS, M] {
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	return &TreeWriter[MS, M]{
+		tree:      FromArray(measurer, [][]byte{}),
+		chunkSize: chunkSize,
+		stage:     make([]byte, 0, chunkSize),
+	}
+}
+
+// Add bytes to the tree, appending each chunk as it fills up.
+// This never returns an error.
+func (w *TreeWriter[MS, M]) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		count := copy(w.stage[len(w.stage):w.chunkSize], p)
+		w.stage = w.stage[:len(w.stage)+count]
+		p = p[count:]
+		if len(w.stage) == w.chunkSize {
+			w.seal()
+		}
+	}
+	return n, nil
+}
+
+// Add a string's bytes to the tree, appending each chunk as it fills up.
+// This never returns an error.
+func (w *TreeWriter[MS, M]) WriteString(s string) (int, error) {
+	n := len(s)
+	for len(s) > 0 {
+		count := copy(w.stage[len(w.stage):w.chunkSize], s)
+		w.stage = w.stage[:len(w.stage)+count]
+		s = s[count:]
+		if len(w.stage) == w.chunkSize {
+			w.seal()
+		}
+	}
+	return n, nil
+}
+
+// Append the partially filled chunk to the tree, if there is one.
+func (w *TreeWriter[MS, M]) Flush() {
+	if len(w.stage) > 0 {
+		w.seal()
+	}
+}
+
+func (w *TreeWriter[MS, M]) seal() {
+	w.tree = w.tree.AddLast(w.stage)
+	w.stage = make([]byte, 0, w.chunkSize)
+}
+
+// Return the tree of the chunks written so far. This does not include
+// a partially filled chunk unless Flush is called first.
+func (w *TreeWriter[MS, M]) Tree() FingerTree[MS, []byte, M] {
+	return w.tree
+}
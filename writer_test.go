@@ -0,0 +1,57 @@
+package lazyfingertree
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+)
+
+type byteLength struct{}
+
+func (m byteLength) Identity() int {
+	return 0
+}
+
+func (m byteLength) Measure(b []byte) int {
+	return len(b)
+}
+
+func (m byteLength) Sum(a int, b int) int {
+	return a + b
+}
+
+func TestTreeWriter(t *testing.T) {
+	w := NewTreeWriter[byteLength](byteLength{}, 4)
+	var _ io.StringWriter = w
+	expected := bytes.Buffer{}
+	for i := 0; i < 20; i++ {
+		s := fmt.Sprint(i, " ")
+		if i%2 == 0 {
+			fmt.Fprint(w, s)
+		} else {
+			io.WriteString(w, s)
+		}
+		expected.WriteString(s)
+		w.Write(nil)
+	}
+	sealed := w.Tree()
+	failIfNot(t, sealed.Measure() == expected.Len()/4*4)
+	w.Flush()
+	w.Flush()
+	tree := w.Tree()
+	failIfNot(t, tree.Measure() == expected.Len())
+	result := bytes.Buffer{}
+	tree.Each(func(chunk []byte) bool {
+		failIfNot(t, len(chunk) > 0 && len(chunk) <= 4)
+		result.Write(chunk)
+		return true
+	})
+	failIfNot(t, bytes.Equal(result.Bytes(), expected.Bytes()))
+	big := bytes.Repeat([]byte("abc"), 7)
+	w = NewTreeWriter[byteLength](byteLength{}, 5)
+	n, err := w.Write(big)
+	failIfNot(t, n == len(big) && err == nil)
+	w.Flush()
+	failIfNot(t, w.Tree().Len() == 5 && w.Tree().PeekLast()[0] == 'c')
+}
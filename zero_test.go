@@ -0,0 +1,99 @@
+package lazyfingertree
+
+import (
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Every method of a zero tree either works like an empty tree or panics with a
+// package error, never with a nil dereference
+func TestZeroTreeMethods(t *testing.T) {
+	var zero FingerTree[width[int, int], int, int]
+	value := reflect.ValueOf(zero)
+	typ := value.Type()
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		args := []reflect.Value{value}
+		for j := 1; j < method.Type.NumIn(); j++ {
+			args = append(args, zeroArg(method.Type.In(j)))
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err, ok := r.(error)
+					if !ok || !errors.Is(err, ErrFingerTree) {
+						t.Errorf("%s panicked with %v", method.Name, r)
+					}
+				}
+			}()
+			if method.Type.IsVariadic() {
+				method.Func.CallSlice(args)
+			} else {
+				method.Func.Call(args)
+			}
+		}()
+	}
+}
+
+// Return a zero value for a parameter, with functions that return zero values and
+// usable writers and hashes
+func zeroArg(typ reflect.Type) reflect.Value {
+	switch typ {
+	case reflect.TypeFor[io.Writer]():
+		return reflect.ValueOf(io.Discard)
+	case reflect.TypeFor[hash.Hash]():
+		return reflect.ValueOf(sha256.New())
+	}
+	if typ.Kind() != reflect.Func {
+		return reflect.Zero(typ)
+	}
+	return reflect.MakeFunc(typ, func([]reflect.Value) []reflect.Value {
+		results := make([]reflect.Value, typ.NumOut())
+		for i := range results {
+			results[i] = reflect.Zero(typ.Out(i))
+		}
+		return results
+	})
+}
+
+func TestZeroTree(t *testing.T) {
+	var zero FingerTree[width[int, int], int, int]
+	failIfNot(t, zero.IsZero() && zero.IsEmpty() && zero.Len() == 0)
+	failIfNot(t, zero.ToSlice() != nil && len(zero.ToSlice()) == 0 && len(zero.ToSliceReverse()) == 0)
+	zero.Each(func(int) bool {
+		t.Fail()
+		return true
+	})
+	zero.EachReverse(func(int) bool {
+		t.Fail()
+		return true
+	})
+	left, right := zero.Split(func(int) bool { return true })
+	failIfNot(t, left.IsZero() && right.IsZero())
+	left, right, err := zero.SplitStrict(func(int) bool { return true })
+	failIfNot(t, err == nil && left.IsZero() && right.IsZero())
+	failIfNot(t, zero.TakeUntil(func(int) bool { return true }).IsZero())
+	failIfNot(t, zero.DropUntil(func(int) bool { return true }).IsZero())
+	failIfNot(t, strings.Contains(zero.String(), "zero"))
+	tree := newTree(1, 2)
+	failIfNot(t, same(zero.Concat(tree).ToSlice(), []int{1, 2}) && same(tree.Concat(zero).ToSlice(), []int{1, 2}))
+	for _, f := range []func(){
+		func() { zero.AddFirst(1) },
+		func() { zero.AddLast(1) },
+		func() { zero.Measure() },
+	} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				failIfNot(t, errors.Is(err, ErrZeroTree))
+			}()
+			f()
+			t.Fail()
+		}()
+	}
+}